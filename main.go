@@ -3,60 +3,721 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"net/http"
 	"sitemap_builder/parse"
 )
 
 // main is the entry point of the sitemap builder application.
 // It parses command-line flags, crawls the specified website using BFS algorithm,
-// and outputs a valid XML sitemap to stdout.
+// and outputs a valid XML sitemap to stdout or to the file named by -out.
 func main() {
-	// Create an HTTP client with a reasonable timeout to prevent hanging requests
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// Parse command-line arguments for URL and crawling depth
-	urlPtr := flag.String("url", "https://gophercises.com", "URL to fetch and parse")
+	// Parse command-line arguments for URL, crawling depth and output destination
+	var startURLs []string
+	flag.Func("url", "Starting URL (or several, comma-separated); repeatable, and more may follow the flags as arguments (default https://gophercises.com)", func(value string) error {
+		startURLs = append(startURLs, value)
+		return nil
+	})
 	maxDepth := flag.Int("depth", 3, "Maximum number of links deep to traverse")
+	strategy := flag.String("strategy", "bfs", "Crawl order: bfs (level by level, concurrently) or dfs (deepest links first, one page at a time)")
+	concurrency := flag.Int("concurrency", 1, "Number of pages to fetch in parallel")
+	delay := flag.Duration("delay", 0, "Minimum pause between requests of each worker (e.g. 500ms)")
+	maxCrawlDelay := flag.Duration("max-crawl-delay", 30*time.Second, "Upper bound for a robots.txt Crawl-delay (0 = no limit)")
+	perHostRPS := flag.Float64("per-host-rps", 0, "Maximum requests per second to each host across all workers (0 = unlimited)")
+	seedFromRobots := flag.Bool("seed-from-robots", false, "Also start from the pages listed in the sitemaps advertised by robots.txt")
+	seedSitemap := flag.String("seed-sitemap", "", "Also start from every page listed in this sitemap or sitemap index URL (.xml or .xml.gz), such as the one being regenerated")
+	ignoreRobots := flag.Bool("ignore-robots", false, "Do not fetch robots.txt; crawl disallowed paths and ignore Crawl-delay (for your own staging sites)")
+	allowUnreachableRobots := flag.Bool("allow-unreachable-robots", false, "Crawl sites whose robots.txt cannot be fetched or answers 5xx as if they had none, instead of skipping them")
+	userAgent := flag.String("user-agent", parse.DefaultUserAgent, "User-Agent header sent with every request")
+	timeout := flag.Duration("timeout", parse.DefaultTimeout, "Time limit for each request, including reading the body (e.g. 30s, 2m)")
+	auth := flag.String("auth", "", "HTTP Basic Auth credentials as user:password, sent with every request (default $SITEMAP_AUTH, which unlike flags is not shown by ps)")
+	headers := flag.String("headers", "", "Comma-separated Key:Value request headers sent with every request")
+	proxy := flag.String("proxy", "", "HTTP, HTTPS or SOCKS5 proxy URL (default: HTTP_PROXY/HTTPS_PROXY/NO_PROXY)")
+	retries := flag.Int("retries", 2, "Number of retries for pages failing with network errors, 429 or 5xx")
+	outPath := flag.String("out", "", "File to write the sitemap to (default: stdout)")
+	flag.StringVar(outPath, "output", "", "Alias for -out")
+	outPrefix := flag.String("out-prefix", "sitemap", "Path prefix for split sitemap files and the sitemap index")
+	publicBase := flag.String("public-base", "", "Public base URL where split sitemap files will be served")
+	splitByPath := flag.Bool("split-by-path", false, "Write one XML sitemap per top-level path section (<out-prefix>-blog.xml, ...) plus an index")
+	gzipOutput := flag.Bool("gzip", false, "Gzip-compress the sitemap (implied when -out ends in .gz)")
+	images := flag.Bool("images", false, "Include <img> sources of each page using the image sitemap extension")
+	videos := flag.Bool("videos", false, "Include <video> elements of each page using the video sitemap extension")
+	hreflang := flag.Bool("hreflang", false, "Include each page's <link rel=\"alternate\" hreflang> variants as <xhtml:link> elements")
+	lastmod := flag.String("lastmod", "on", "Emit <lastmod> from Last-Modified headers: on or off")
+	changeFreq := flag.String("changefreq", "", "Stamp every entry with this <changefreq> (always, hourly, daily, weekly, monthly, yearly, never)")
+	priority := flag.String("priority", "", "Stamp every entry with this <priority> between 0.0 and 1.0")
+	priorityByDepth := flag.Bool("priority-by-depth", false, "Derive each entry's <priority> from its crawl depth (1.0 at depth 0, -0.2 per level, floor 0.1)")
+	compact := flag.Bool("compact", false, "Write XML without indentation")
+	extended := flag.Bool("extended", false, "Add each page's <title> to the XML sitemap as a comment in its <url> entry")
+	graphPath := flag.String("graph", "", "Also write the crawl's link graph in Graphviz DOT format to this file")
+	reportPath := flag.String("report", "", "Also write an HTML crawl report for stakeholders to this file")
+	diffPath := flag.String("diff", "", "Compare the crawl with this existing sitemap (.xml or .xml.gz), print added and removed URLs and exit with status 3 if they differ")
+	validate := flag.Bool("validate", false, "Check every listed URL with HEAD (falling back to GET) and print broken (4xx/5xx or unreachable) ones to stderr")
+	skipNonHTML := flag.Bool("skip-non-html", false, "Leave PDFs, images and other files that are not HTML out of the sitemap (they are never parsed for links)")
+	includeNoindex := flag.Bool("include-noindex", false, "List pages marked noindex by a robots meta tag or X-Robots-Tag header")
+	ignoreNofollow := flag.Bool("ignore-nofollow", false, "Follow links on pages marked nofollow (for your own staging sites)")
+	noCanonical := flag.Bool("no-canonical", false, "List pages under their fetched URL, ignoring <link rel=\"canonical\">")
+	trailingSlash := flag.String("trailing-slash", parse.TrailingSlashKeep, "Spell URLs with or without a trailing slash: keep, add (except file-like paths such as /feed.xml) or strip")
+	includeSubdomains := flag.Bool("include-subdomains", false, "Also crawl the subdomains of the start URL's registrable domain, such as docs.example.com for www.example.com")
+	strictScheme := flag.Bool("strict-scheme", false, "Treat http:// links on https pages, and https:// links on http pages, as external")
+	httpsOnly := flag.Bool("https-only", false, "Upgrade every http:// start URL and link to https:// with a warning, and never fetch or list http:// URLs")
+	preferHTTPS := flag.Bool("prefer-https", true, "Treat http:// links to the host of an https start URL as their https:// form, falling back to http if https is unreachable")
+	stripQuery := flag.Bool("strip-query", false, "Remove query strings from discovered URLs so their variants collapse into one entry")
+	checkpointPath := flag.String("checkpoint", "", "Save the crawl state to this JSON file before every level, and continue the crawl saved there if it has the same start URLs")
+	fresh := flag.Bool("fresh", false, "With -checkpoint, start from scratch even if the file holds a crawl to continue")
+	resume := flag.Bool("resume", false, "Deprecated: crawls with -checkpoint now resume unless -fresh is given")
+	cacheDir := flag.String("cache-dir", "", "Keep page validators in this directory and recrawl with conditional requests, reusing pages that answer 304 Not Modified")
+	maxTime := flag.Duration("max-time", 0, "Stop the crawl after this long and write the pages found so far, exiting with status 4 (0 = no limit)")
+	maxPages := flag.Int("max-pages", 0, "Stop fetching new pages once this many have been fetched (0 = no limit)")
+	includeUnfetched := flag.Bool("include-unfetched", false, "With -max-pages, still list URLs that were discovered but not fetched")
+	verify := flag.Bool("verify", false, "Only list URLs confirmed to return 200 without redirecting, checking pages at the depth limit too")
+	longURLs := flag.String("max-url-len", parse.LongURLWarn, "What to do with URLs longer than 2048 characters once escaped: warn, skip or truncate")
+	sortOutput := flag.Bool("sort", false, "Sort entries by URL (then depth) and collapse duplicates, for reproducible output")
+	showProgress := flag.Bool("progress", true, "Print a line to stderr before every fetch")
+	verbose := flag.Bool("verbose", false, "Log structured diagnostics (failed fetches, retries, skipped pages) to stderr")
+	depthReport := flag.Bool("max-depth-report", false, "Print a histogram of listed URLs per crawl depth to stderr after writing the sitemap")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics of the crawl on /metrics at this address, such as :9090, until the crawl ends")
+	stats := flag.Bool("stats", false, "Print a summary of the crawl (pages visited, failed, skipped, duration) to stderr")
+	quiet := flag.Bool("quiet", false, "Suppress all output on stderr except errors")
+	format := flag.String("format", "xml", "Comma-separated output formats: xml, txt, json or csv (several require -out as base name)")
+	var exclude, include []string
+	flag.Func("exclude", "Regular expression; matching URLs are not crawled or listed; repeatable, one expression per occurrence", func(value string) error {
+		exclude = append(exclude, value)
+		return nil
+	})
+	flag.Func("include", "Regular expression; only URLs whose path matches one are crawled and listed; repeatable, one expression per occurrence", func(value string) error {
+		include = append(include, value)
+		return nil
+	})
+	var keepQueryParams []string
+	flag.Func("keep-query-param", "With -strip-query, keep this query parameter (or several, comma-separated); repeatable", func(value string) error {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				keepQueryParams = append(keepQueryParams, name)
+			}
+		}
+		return nil
+	})
+	var cookies []*http.Cookie
+	flag.Func("cookie", "Session cookie as name=value (or several separated by \"; \"); repeatable", func(value string) error {
+		parsed, err := http.ParseCookie(value)
+		if err != nil {
+			return err
+		}
+		cookies = append(cookies, parsed...)
+		return nil
+	})
 	flag.Parse()
 
-	// Display crawling configuration
-	fmt.Println("Max Depth:", *maxDepth)
-	fmt.Println("Fetching URL:", *urlPtr)
-	fmt.Println("--------------------------------------------------------------------------")
+	// Validate enumerated and ranged flag values before crawling
+	if *lastmod != "on" && *lastmod != "off" {
+		fatal("Error:", fmt.Errorf("invalid -lastmod %q (expected on or off)", *lastmod))
+	}
+	if *changeFreq != "" {
+		if err := parse.ValidateChangeFreq(*changeFreq); err != nil {
+			fatal("Error: -changefreq:", err)
+		}
+	}
+	var fixedPriority *float64
+	if *priority != "" {
+		p, err := strconv.ParseFloat(*priority, 64)
+		if err != nil {
+			fatal("Error: -priority:", err)
+		}
+		if err := parse.ValidatePriority(p); err != nil {
+			fatal("Error: -priority:", err)
+		}
+		fixedPriority = &p
+	}
+	if *priority != "" && *priorityByDepth {
+		fatal("Error:", fmt.Errorf("-priority and -priority-by-depth are mutually exclusive"))
+	}
 
-	// Fetch and parse the initial HTML document
-	doc, err := parse.FetchAndParse(*urlPtr, client)
+	// Split the starting URLs, given to -url or as arguments, and derive the sites they
+	// belong to; robots.txt, cookies and advertised sitemaps are those of the first one
+	startURLs = append(startURLs, flag.Args()...)
+	if len(startURLs) == 0 {
+		startURLs = []string{defaultStartURL}
+	}
+	var seedURLs []string
+	for _, rawURL := range strings.Split(strings.Join(startURLs, ","), ",") {
+		if rawURL = strings.TrimSpace(rawURL); rawURL != "" {
+			seedURLs = append(seedURLs, rawURL)
+		}
+	}
+	origins, err := parse.SeedOrigins(seedURLs)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		fatal("Error: -url:", err)
+	}
+	baseDomain := origins[0]
+	if scheme, host, _ := strings.Cut(baseDomain, "://"); *httpsOnly && strings.EqualFold(scheme, "http") {
+		baseDomain = "https://" + host // The crawl, and so robots.txt and cookies, are on the https site
 	}
 
-	// Use the provided URL as the base domain for internal link detection
-	baseDomain := *urlPtr
-
-	// Extract all internal links from the initial page
-	initialLinks := parse.ExtractLinks(doc, baseDomain)
+	// Compile the URL filters up front so a typo is reported before crawling
+	excludePatterns, err := compilePatterns(exclude)
+	if err != nil {
+		fatal("Error: -exclude:", err)
+	}
+	includePatterns, err := compilePatterns(include)
+	if err != nil {
+		fatal("Error: -include:", err)
+	}
 
-	// Perform breadth-first search crawling to discover all internal pages
-	allLinks, err := parse.CrawlBFS(initialLinks, *maxDepth, client)
+	requestHeaders, err := parse.ParseHeaders(*headers)
 	if err != nil {
-		fmt.Println("Error during crawling:", err)
-		return
+		fatal("Error: -headers:", err)
+	}
+
+	// Credentials on the command line are visible to every user through ps, so they can
+	// come from the environment instead
+	var basicAuth *parse.BasicAuthConfig
+	if *auth == "" {
+		*auth = os.Getenv("SITEMAP_AUTH")
+	}
+	if *auth != "" {
+		basicAuth, err = parse.ParseBasicAuth(*auth)
+		if err != nil {
+			fatal("Error: -auth:", err)
+		}
+		if !strings.HasPrefix(strings.ToLower(baseDomain), "https://") {
+			fmt.Fprintln(os.Stderr, "Warning: sending -auth credentials over plain http; use an https:// start URL and -https-only")
+		}
+	}
+
+	var proxyURL *url.URL
+	if *proxy != "" {
+		u, err := url.Parse(*proxy)
+		if err != nil {
+			fatal("Error: -proxy:", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			fatal("Error: -proxy:", fmt.Errorf("unsupported proxy scheme in %q (expected http, https or socks5)", *proxy))
+		}
+		proxyURL = u
+	}
+
+	if !slices.Contains(parse.LongURLPolicies, *longURLs) {
+		fatal("Error:", fmt.Errorf("invalid -max-url-len %q (expected one of %v)", *longURLs, parse.LongURLPolicies))
+	}
+
+	if *perHostRPS < 0 {
+		fatal("Error:", fmt.Errorf("invalid -per-host-rps %v (must not be negative)", *perHostRPS))
+	}
+	if !slices.Contains(parse.TrailingSlashPolicies, *trailingSlash) {
+		fatal("Error:", fmt.Errorf("invalid -trailing-slash %q (expected one of %v)", *trailingSlash, parse.TrailingSlashPolicies))
+	}
+	crawl, ok := crawlStrategies[*strategy]
+	if !ok {
+		fatal("Error:", fmt.Errorf("invalid -strategy %q (expected bfs or dfs)", *strategy))
+	}
+	if *strategy == "dfs" && *checkpointPath != "" {
+		fatal("Error:", fmt.Errorf("-checkpoint requires -strategy=bfs"))
+	}
+	if len(keepQueryParams) > 0 && !*stripQuery {
+		fatal("Error:", fmt.Errorf("-keep-query-param requires -strip-query"))
+	}
+	if *resume && *fresh {
+		fatal("Error:", fmt.Errorf("-resume and -fresh are mutually exclusive"))
+	}
+	if (*resume || *fresh) && *checkpointPath == "" {
+		fatal("Error:", fmt.Errorf("-resume and -fresh require -checkpoint"))
+	}
+	if *maxTime < 0 {
+		fatal("Error:", fmt.Errorf("invalid -max-time %v (must not be negative)", *maxTime))
+	}
+	if *maxPages < 0 {
+		fatal("Error:", fmt.Errorf("invalid -max-pages %d (must not be negative)", *maxPages))
+	}
+
+	// Resolve the output encoders before crawling so a typo doesn't waste a whole crawl
+	var formats []string
+	for _, name := range strings.Split(*format, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := encoders[name]; !ok {
+			fatal("Error:", fmt.Errorf("unknown -format %q (expected xml, txt, json or csv)", name))
+		}
+		if !slices.Contains(formats, name) {
+			formats = append(formats, name)
+		}
+	}
+	if *compact || *extended {
+		indent := "  "
+		if *compact {
+			indent = ""
+		}
+		encoders["xml"] = xmlDocumentWriter(indent, *extended)
+	}
+	if len(formats) > 1 && *outPath == "" {
+		fatal("Error:", fmt.Errorf("-out is required as the base name when writing several formats"))
+	}
+	if *splitByPath {
+		if !slices.Contains(formats, "xml") {
+			fatal("Error:", fmt.Errorf("-split-by-path requires the xml format"))
+		}
+		if *publicBase == "" {
+			fatal("Error:", fmt.Errorf("-split-by-path requires -public-base to build the sitemap index"))
+		}
+	}
+
+	// A .gz destination only makes sense with compressed content
+	if strings.HasSuffix(*outPath, ".gz") {
+		*gzipOutput = true
+	}
+
+	// Fail fast if the output destinations cannot be written, before spending time crawling
+	for _, path := range []string{*outPath, *outPrefix, *graphPath, *reportPath} {
+		if path == "" {
+			continue
+		}
+		if err := checkWritableDir(filepath.Dir(path)); err != nil {
+			fatal("Error:", err)
+		}
+	}
+
+	// Route informational messages to stderr, unless they were silenced
+	if *quiet {
+		info = io.Discard
+	} else if *verbose {
+		parse.SetLogger(parse.NewStderrLogger())
+	}
+
+	// Display crawling configuration on stderr so stdout only ever carries the sitemap
+	fmt.Fprintln(info, "Max Depth:", *maxDepth)
+	fmt.Fprintln(info, "Fetching URL:", strings.Join(seedURLs, ", "))
+	if len(origins) > 1 {
+		fmt.Fprintf(info, "Start URLs span %d sites; each follows its own robots.txt, cookies apply to %s only\n", len(origins), baseDomain)
+	}
+	fmt.Fprintln(info, "--------------------------------------------------------------------------")
+
+	// Collect the crawl settings shared by every request
+	opts := parse.CrawlOptions{
+		UserAgent:         *userAgent,
+		Concurrency:       *concurrency,
+		Delay:             *delay,
+		MaxCrawlDelay:     *maxCrawlDelay,
+		Images:            *images,
+		Videos:            *videos,
+		Hreflang:          *hreflang,
+		MaxRetries:        *retries,
+		Timeout:           *timeout,
+		ProxyURL:          proxyURL,
+		Cookies:           cookies,
+		Headers:           requestHeaders,
+		BasicAuth:         basicAuth,
+		Verify:            *verify,
+		IncludeNoindex:    *includeNoindex,
+		SkipNonHTML:       *skipNonHTML,
+		IgnoreNofollow:    *ignoreNofollow,
+		IgnoreCanonical:   *noCanonical,
+		MaxPages:          *maxPages,
+		TrailingSlash:     *trailingSlash,
+		PreferHTTPS:       *preferHTTPS,
+		HTTPSOnly:         *httpsOnly,
+		StrictScheme:      *strictScheme,
+		IncludeSubdomains: *includeSubdomains,
+		StripQuery:        *stripQuery,
+		KeepQueryParams:   keepQueryParams,
+		CheckpointFile:    *checkpointPath,
+		Resume:            !*fresh,
+		IncludeUnfetched:  *includeUnfetched,
+		ExcludePatterns:   excludePatterns,
+		IncludePatterns:   includePatterns,
+	}
+
+	if *showProgress && !*quiet {
+		opts.ProgressWriter = os.Stderr
+	}
+
+	// Queue bursts to the same host, with no more than one request in reserve
+	if *perHostRPS > 0 {
+		opts.Limiter = parse.NewPerHostLimiter(*perHostRPS, 1)
+	}
+
+	// Create an HTTP client with the requested timeout to prevent hanging requests
+	client := parse.NewHTTPClient(opts)
+	if len(cookies) > 0 {
+		client.Jar, err = parse.NewCookieJar(baseDomain, cookies)
+		if err != nil {
+			fatal("Error:", err)
+		}
+	}
+
+	// Record the link structure and crawl summary only when they were requested
+	if *graphPath != "" {
+		opts.Graph = parse.NewGraph()
+	}
+
+	if *reportPath != "" {
+		opts.Report = &parse.Report{}
+	}
+
+	// Let a monitoring system scrape the progress of the crawl while it runs
+	var stopMetrics func()
+	if *metricsAddr != "" {
+		var metrics *crawlMetrics
+		metrics, stopMetrics, err = serveMetrics(*metricsAddr)
+		if err != nil {
+			fatal("Error:", err)
+		}
+		opts.Metrics = metrics
+		fmt.Fprintf(info, "Serving metrics on %s/metrics\n", *metricsAddr)
+	}
+
+	// Recrawl with the validators of the previous run so unchanged pages are not parsed again
+	if *cacheDir != "" {
+		opts.Cache, err = parse.LoadPageCache(*cacheDir)
+		if err != nil {
+			fatal("Error:", err)
+		}
+		if n := opts.Cache.Len(); n > 0 {
+			fmt.Fprintf(info, "Loaded %d cached pages from %s\n", n, *cacheDir)
+		}
+	}
+
+	// Every starting URL becomes a depth-0 seed of the crawl
+	var seeds []parse.Link
+	for _, rawURL := range seedURLs {
+		seeds = append(seeds, parse.Link{Href: rawURL})
+	}
+
+	// From here on Ctrl-C or SIGTERM cancels sigCtx, so that reading robots.txt and the
+	// sitemaps that seed the crawl can be interrupted as well as the crawl itself; a second
+	// signal falls back to the default behavior and exits at once
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+
+	// Honor the site's robots.txt unless told otherwise; without one we can still crawl,
+	// just unfiltered, but one that cannot be read forbids the whole site (RFC 9309)
+	if !*ignoreRobots {
+		opts.Robots, err = parse.NewRobotsFilter(sigCtx, baseDomain, client, opts)
+		if *allowUnreachableRobots {
+			opts.Robots.AllowUnreachable()
+		}
+		switch {
+		case sigCtx.Err() != nil:
+			fatal("Error reading robots.txt:", err)
+		case errors.Is(err, parse.ErrRobotsUnreachable) && !*allowUnreachableRobots:
+			fatal("Error:", fmt.Errorf("%w; the site may not be crawled until it can be read (-allow-unreachable-robots crawls it anyway)", err))
+		case err != nil:
+			fmt.Fprintln(info, "Warning: ignoring robots.txt:", err)
+		}
+	}
+	if crawlDelay := opts.Robots.CrawlDelay(); crawlDelay > 0 {
+		fmt.Fprintf(info, "robots.txt asks for a Crawl-delay of %s; waiting %s between requests\n", crawlDelay, opts.EffectiveDelay())
+	}
+
+	// Every other start site follows its own robots.txt and Crawl-delay, read now so that
+	// problems show up before the crawl
+	for _, origin := range origins[1:] {
+		robots, err := opts.Robots.For(origin)
+		switch {
+		case errors.Is(err, parse.ErrRobotsUnreachable) && !*allowUnreachableRobots:
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v (-allow-unreachable-robots crawls it anyway)\n", origin, err)
+		case err != nil:
+			fmt.Fprintf(info, "Warning: ignoring robots.txt of %s: %v\n", origin, err)
+		}
+		if crawlDelay := robots.CrawlDelay(); crawlDelay > 0 {
+			fmt.Fprintf(info, "robots.txt of %s asks for a Crawl-delay of %s between requests to it\n", origin, crawlDelay)
+		}
+	}
+
+	// Also start from every page listed in the sitemaps the start sites advertise in
+	// robots.txt, keeping only those the crawl may visit
+	if *seedFromRobots {
+		var sitemaps []string
+		for _, origin := range origins {
+			if *ignoreRobots {
+				advertised, err := parse.DiscoverSitemap(sigCtx, origin, client, opts)
+				if err != nil {
+					fatal("Error discovering sitemaps:", err)
+				}
+				sitemaps = append(sitemaps, advertised...)
+				continue
+			}
+			robots, _ := opts.Robots.For(origin)
+			sitemaps = append(sitemaps, robots.Sitemaps()...)
+		}
+		pages, err := parse.FetchSitemapURLs(sigCtx, sitemaps, client, opts)
+		if err != nil {
+			fatal("Error reading advertised sitemaps:", err)
+		}
+
+		added := sitemapSeeds(pages, origins, opts.Robots)
+		seeds = append(seeds, added...)
+		fmt.Fprintf(info, "Seeded %d URLs from the sitemaps advertised in robots.txt\n", len(added))
 	}
 
-	// Generate XML sitemap from discovered links
-	sitemapXML, err := parse.EncodeXML(allLinks)
+	// Also start from every page of an existing sitemap, the best list of starting points
+	// when regenerating it; pages that have since disappeared answer with an error status,
+	// so they are left out of the output and listed among the failures of -report
+	if *seedSitemap != "" {
+		pages, err := parse.FetchSitemapURLs(sigCtx, []string{*seedSitemap}, client, opts)
+		if err != nil {
+			fatal("Error reading -seed-sitemap:", err)
+		}
+		added := sitemapSeeds(pages, origins, opts.Robots)
+		seeds = append(seeds, added...)
+		fmt.Fprintf(info, "Seeded %d URLs from %s\n", len(added), *seedSitemap)
+	}
+
+	// Crawl breadth-first, or depth-first with -strategy=dfs, to discover all internal
+	// pages. Ctrl-C or SIGTERM stops the crawl once the current BFS level is done and the
+	// pages found so far are still written. -max-time ends the crawl at once when its
+	// budget is spent, cancelling in-flight requests, and the pages found are written too.
+	ctx := sigCtx
+	if *maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxTime)
+		defer cancel()
+	}
+	result, err := crawl(ctx, seeds, parse.WithOptions(opts), parse.WithMaxDepth(*maxDepth), parse.WithHTTPClient(client))
+	stop()
+	if stopMetrics != nil {
+		stopMetrics()
+	}
+
+	// Only a complete crawl replaces the cache, which would otherwise forget the pages it
+	// did not get to
+	if opts.Cache != nil && err == nil {
+		if err := opts.Cache.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+		}
+		if result.PagesUnchanged > 0 {
+			fmt.Fprintf(info, "%d pages not modified since the previous crawl\n", result.PagesUnchanged)
+		}
+	}
+	interrupted := errors.Is(err, context.Canceled)
+	timedOut := errors.Is(err, context.DeadlineExceeded)
+	switch {
+	case interrupted:
+		fmt.Fprintln(os.Stderr, "Warning:", err, "- writing partial sitemap")
+	case timedOut:
+		fmt.Fprintf(os.Stderr, "Warning: crawl stopped after -max-time of %s - writing partial sitemap\n", *maxTime)
+	case err != nil:
+		fatal("Error during crawling:", err)
+	}
+	for _, e := range result.Errors {
+		if e.Depth == 0 {
+			fmt.Fprintln(os.Stderr, "Warning: start URL could not be crawled:", e.Error())
+		}
+	}
+	if result.Resumed {
+		fmt.Fprintln(info, "Resumed the crawl saved in", *checkpointPath)
+	}
+	if result.Truncated {
+		fmt.Fprintf(os.Stderr, "Warning: crawl truncated at %d pages (-max-pages)\n", result.PagesVisited)
+	}
+	allLinks := result.Links
+
+	// Deal with URLs the protocol considers too long, reporting what happened to them
+	allLinks, longOffenders, err := parse.EnforceLocLength(allLinks, *longURLs)
 	if err != nil {
-		fmt.Println("Error encoding XML:", err)
-		return
+		fatal("Error:", err)
+	}
+	if len(longOffenders) > 0 {
+		fmt.Fprintf(info, "%d URLs exceed %d characters (-max-url-len=%s)\n", len(longOffenders), parse.MaxLocLength, *longURLs)
+		if *longURLs == parse.LongURLSkip {
+			result.SkippedLongURLs = len(longOffenders)
+			if opts.Report != nil {
+				opts.Report.SkippedLongURLs = len(longOffenders)
+			}
+		}
 	}
 
-	// Output the final sitemap to stdout
-	fmt.Println(sitemapXML)
+	// Write the link graph and report alongside whatever sitemap output follows
+	if opts.Graph != nil {
+		if err := writeFileAtomic(*graphPath, opts.Graph.WriteDOT); err != nil {
+			fatal("Error writing graph:", err)
+		}
+		fmt.Fprintln(info, "Graph written to", *graphPath)
+	}
+
+	if opts.Report != nil {
+		writeReport := func(w io.Writer) error {
+			return opts.Report.WriteHTML(w, reportSlowestPages)
+		}
+		if err := writeFileAtomic(*reportPath, writeReport); err != nil {
+			fatal("Error writing report:", err)
+		}
+		fmt.Fprintln(info, "Report written to", *reportPath)
+	}
+
+	// Make the output independent of discovery order when requested
+	if *sortOutput {
+		allLinks = parse.SortLinks(allLinks)
+	}
+
+	// Compare against the previously published sitemap before writing the new one
+	changed := false
+	if *diffPath != "" {
+		changed, err = printDiff(*diffPath, allLinks)
+		if err != nil {
+			fatal("Error comparing with previous sitemap:", err)
+		}
+	}
+
+	// Apply the entry-level hints requested on the command line
+	for i := range allLinks {
+		// Drop modification dates when byte-compatible output without <lastmod> is wanted
+		if *lastmod == "off" {
+			allLinks[i].LastMod = ""
+		}
+		if *changeFreq != "" {
+			allLinks[i].ChangeFreq = *changeFreq
+		}
+		if *priority != "" {
+			allLinks[i].Priority = fixedPriority
+		}
+		if *priorityByDepth {
+			p := parse.DepthPriority(allLinks[i].Depth)
+			allLinks[i].Priority = &p
+		}
+	}
+
+	// Report dead links before the sitemap is written; like the crawl, the checks can be
+	// interrupted and the sitemap is still written
+	if *validate {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := printBrokenLinks(ctx, allLinks, client, opts)
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: link validation interrupted:", err)
+		}
+	}
+
+	// Write every requested format from the same crawl results. Each file is written
+	// atomically, so a failure leaves the other formats intact.
+	failed := false
+	for _, name := range formats {
+		dest := *outPath
+		if len(formats) > 1 {
+			dest = formatPath(*outPath, name, *gzipOutput)
+		}
+		if err := writeOutput(allLinks, name, dest, *outPrefix, *publicBase, *gzipOutput, *splitByPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s sitemap: %v\n", name, err)
+			failed = true
+		}
+	}
+	// Summarize the crawl once the sitemap is out; explicitly requested, so not silenced by -quiet
+	if *stats {
+		if err := result.WriteSummary(os.Stderr); err != nil {
+			fatal("Error writing statistics:", err)
+		}
+	}
+	if *depthReport {
+		if err := printDepthHistogram(os.Stderr, allLinks); err != nil {
+			fatal("Error writing depth histogram:", err)
+		}
+	}
+
+	// An interrupted crawl is an error too, even though its partial sitemap was written
+	if failed || interrupted {
+		os.Exit(1)
+	}
+	if timedOut {
+		os.Exit(timeLimitExitCode)
+	}
+
+	// Let CI gate on changes once the new sitemap has been written
+	if changed {
+		os.Exit(diffExitCode)
+	}
+}
+
+// info receives informational messages such as the crawl banner and the names of
+// written files. It is stderr, or io.Discard with -quiet.
+var info io.Writer = os.Stderr
+
+// diffExitCode is the exit status used when -diff finds differences, distinct from
+// the status 1 used for errors.
+const diffExitCode = 3
+
+// timeLimitExitCode is the exit status used after a partial sitemap was written because
+// the crawl ran out of its -max-time budget.
+const timeLimitExitCode = 4
+
+// reportSlowestPages is the number of slowest pages listed in the -report output.
+const reportSlowestPages = 10
+
+// crawlStrategies maps each supported -strategy value to the crawl implementing it.
+var crawlStrategies = map[string]func(context.Context, []parse.Link, ...parse.CrawlOption) (*parse.CrawlResult, error){
+	"bfs": parse.CrawlBFS,
+	"dfs": parse.CrawlDFS,
+}
+
+// encoders maps each supported -format value to the function that writes it.
+var encoders = map[string]func([]parse.Link, io.Writer) error{
+	"xml":  xmlDocumentWriter("  ", false),
+	"txt":  parse.EncodeText,
+	"json": parse.WriteJSON,
+	"csv":  parse.WriteCSV,
+}
+
+// sitemapSeeds turns the pages listed in sitemaps into seeds, keeping only those on one of
+// origins that robots.txt allows the crawl to visit.
+func sitemapSeeds(pages, origins []string, robots *parse.RobotsFilter) []parse.Link {
+	var seeds []parse.Link
+	for _, page := range pages {
+		origin, err := parse.BaseDomain([]string{page})
+		if err != nil || !slices.ContainsFunc(origins, func(o string) bool { return strings.EqualFold(o, origin) }) || !robots.Allowed(page) {
+			continue
+		}
+		seeds = append(seeds, parse.Link{Href: page})
+	}
+	return seeds
+}
+
+// defaultStartURL is crawled when no start URL is given.
+const defaultStartURL = "https://gophercises.com"
+
+// compilePatterns compiles the regular expressions given to the occurrences of a
+// repeatable flag. Every occurrence is exactly one expression, since commas are part of
+// the regular expression syntax, as in a{1,3}; empty values are ignored.
+//
+// Parameters:
+//   - values: The value of every occurrence of the flag
+//
+// Returns:
+//   - []*regexp.Regexp: The compiled expressions, in flag order
+//   - error: The first expression that is not a valid regular expression
+func compilePatterns(values []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, pattern := range values {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// fatal prints an error message to stderr and terminates the program with a non-zero exit code.
+func fatal(prefix string, err error) {
+	fmt.Fprintln(os.Stderr, prefix, err)
+	os.Exit(1)
 }
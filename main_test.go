@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestCompilePatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		matches string
+		want    int
+		wantErr bool
+	}{
+		{"counted repetition", []string{`^/a{1,3}$`}, "/aa", 1, false},
+		{"one pattern per occurrence", []string{`/admin/`, `\?session=`}, "/x?session=1", 2, false},
+		{"alternatives", []string{`^/(docs|blog)/`}, "/blog/post", 1, false},
+		{"empty values ignored", []string{"", "  "}, "", 0, false},
+		{"invalid", []string{`/a(`}, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns, err := compilePatterns(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compilePatterns(%q) error = %v, want error %v", tt.values, err, tt.wantErr)
+			}
+			if len(patterns) != tt.want {
+				t.Fatalf("compilePatterns(%q) = %v, want %d patterns", tt.values, patterns, tt.want)
+			}
+			if tt.matches != "" && !patterns[len(patterns)-1].MatchString(tt.matches) {
+				t.Errorf("%v does not match %q", patterns[len(patterns)-1], tt.matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsShutdownTimeout bounds how long a scrape in progress may delay the exit.
+const metricsShutdownTimeout = 5 * time.Second
+
+// crawlMetrics exports the progress of a crawl in the Prometheus text format. It
+// implements parse.Metrics.
+type crawlMetrics struct {
+	crawled  prometheus.Counter   // Pages fetched, successfully or not
+	failed   prometheus.Counter   // Fetched pages that returned an error
+	queued   prometheus.Gauge     // Pages waiting to be fetched
+	depth    prometheus.Gauge     // Depth of the page fetched last
+	duration prometheus.Histogram // Time spent fetching each page
+}
+
+// newCrawlMetrics creates the crawl metrics and registers them with reg.
+func newCrawlMetrics(reg prometheus.Registerer) *crawlMetrics {
+	m := &crawlMetrics{
+		crawled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sitemap_pages_crawled_total",
+			Help: "Pages fetched, successfully or not.",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sitemap_pages_failed_total",
+			Help: "Fetched pages that could not be retrieved or returned an error status.",
+		}),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sitemap_pages_queued",
+			Help: "Pages waiting to be fetched.",
+		}),
+		depth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sitemap_crawl_depth_current",
+			Help: "Crawl depth of the page fetched last.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "sitemap_fetch_duration_seconds",
+			Help:    "Time spent fetching a page, retries included.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+	reg.MustRegister(m.crawled, m.failed, m.queued, m.depth, m.duration)
+	return m
+}
+
+// Queued implements parse.Metrics.
+func (m *crawlMetrics) Queued(n int) {
+	m.queued.Add(float64(n))
+}
+
+// Visiting implements parse.Metrics.
+func (m *crawlMetrics) Visiting(depth int) {
+	m.queued.Dec()
+	m.depth.Set(float64(depth))
+}
+
+// Fetched implements parse.Metrics.
+func (m *crawlMetrics) Fetched(d time.Duration, failed bool) {
+	m.crawled.Inc()
+	if failed {
+		m.failed.Inc()
+	}
+	m.duration.Observe(d.Seconds())
+}
+
+// serveMetrics starts an HTTP server exposing the crawl metrics on /metrics at addr. The
+// address is bound before returning, so that a port already in use is reported at once.
+//
+// Parameters:
+//   - addr: Address to listen on, such as ":9090" or "127.0.0.1:9090"
+//
+// Returns:
+//   - *crawlMetrics: The metrics to pass in CrawlOptions.Metrics
+//   - func(): Stops the server, waiting briefly for scrapes in progress
+//   - error: The address cannot be listened on
+func serveMetrics(addr string) (*crawlMetrics, func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listening for metrics: %w", err)
+	}
+
+	// A registry of its own keeps the output to the crawl's metrics
+	reg := prometheus.NewRegistry()
+	metrics := newCrawlMetrics(reg)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "Warning: metrics server:", err)
+		}
+	}()
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+		}
+		<-done
+	}
+	return metrics, shutdown, nil
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sitemap_builder/parse"
+)
+
+// writeOutput writes links in one format to dest, or to stdout if dest is empty.
+// XML sitemaps split by section, or above the protocol's URL or size limit, are written
+// as several files tied together by an index, named after prefix.
+//
+// Parameters:
+//   - links: All links to write
+//   - format: Key of the encoder in encoders
+//   - dest: Output file, or "" for stdout
+//   - prefix: Path prefix for split sitemap files and the sitemap index
+//   - publicBase: Absolute base URL under which split sitemap files will be served
+//   - compress: Whether to gzip-compress the output
+//   - bySection: Whether XML is written as one sitemap per top-level path section
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing
+func writeOutput(links []parse.Link, format, dest, prefix, publicBase string, compress, bySection bool) error {
+	encode := encoders[format]
+
+	// Teams owning a section of the site each get their own file
+	if format == "xml" && bySection {
+		return writeSectionSitemaps(links, encode, prefix, publicBase, compress)
+	}
+
+	// Sites above the protocol's URL or size limit get several XML sitemap files tied
+	// together by an index
+	if format == "xml" {
+		chunks, err := parse.ChunkBySize(links, parse.MaxURLsPerSitemap, parse.MaxSitemapBytes)
+		if err != nil {
+			return fmt.Errorf("splitting sitemap: %w", err)
+		}
+		if len(chunks) > 1 {
+			return writeSplitSitemaps(chunks, encode, prefix, publicBase, compress)
+		}
+	}
+
+	// Stream the sitemap in the requested format, compressing it on the fly when requested
+	write := func(w io.Writer) error {
+		return encode(links, w)
+	}
+	if compress {
+		write = gzipped(write)
+	}
+
+	if dest == "" {
+		return write(os.Stdout)
+	}
+	if err := writeFileAtomic(dest, write); err != nil {
+		return err
+	}
+	fmt.Fprintln(info, "Sitemap written to", dest)
+	return nil
+}
+
+// formatPath derives the file name for one of several output formats from the -out base
+// name: a trailing .gz and a format extension are replaced, so "public/sitemap.xml" with
+// format "txt" becomes "public/sitemap.txt".
+func formatPath(base, format string, compress bool) string {
+	base = strings.TrimSuffix(base, ".gz")
+	if _, ok := encoders[strings.TrimPrefix(filepath.Ext(base), ".")]; ok {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	path := base + "." + format
+	if compress {
+		path += ".gz"
+	}
+	return path
+}
+
+// checkWritableDir verifies that dir exists, is a directory, and accepts new files.
+// It is used to reject an unusable -out destination before the crawl starts rather
+// than discovering the problem after minutes of crawling.
+//
+// Parameters:
+//   - dir: The directory that will receive the output file
+//
+// Returns:
+//   - error: A descriptive error if the directory is missing or not writable
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("output directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output directory %s: not a directory", dir)
+	}
+
+	// The only reliable portable writability check is to actually create a file
+	probe, err := os.CreateTemp(dir, ".sitemap-probe-*")
+	if err != nil {
+		return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// writeFileAtomic writes a file to path without ever exposing a partially written file.
+// The content produced by write is first written to a temporary file in the same directory and then
+// renamed over the destination, which is atomic on POSIX filesystems. If anything
+// fails, the temporary file is removed and any existing file at path is left untouched.
+//
+// Parameters:
+//   - path: Destination file path
+//   - write: Function that streams the complete file contents to the provided writer
+//
+// Returns:
+//   - error: Any error that occurred while writing, syncing, or renaming the file
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+
+	// Create the temporary file next to the destination so the rename stays on one filesystem
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file in %s: %w", dir, err)
+	}
+	tmpName := tmp.Name()
+
+	// Clean up the temporary file on any failure path
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return fmt.Errorf("writing %s: %w", tmpName, err)
+	}
+
+	// Flush to stable storage before the rename makes the file visible
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", tmpName, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", tmpName, err)
+	}
+
+	// CreateTemp uses 0600; sitemaps are meant to be served, so use regular file permissions
+	if err := os.Chmod(tmpName, 0o644); err != nil {
+		return fmt.Errorf("setting permissions on %s: %w", tmpName, err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("renaming %s to %s: %w", tmpName, path, err)
+	}
+
+	success = true
+	return nil
+}
+
+// sitemapFile is one file of a sitemap set tied together by a sitemap index.
+type sitemapFile struct {
+	path  string       // Destination of the file, without the .gz suffix added for compression
+	links []parse.Link // The entries of the file
+}
+
+// writeSplitSitemaps writes each chunk of links as a numbered sitemap file (<prefix>-1.xml,
+// <prefix>-2.xml, ...) plus a <prefix>_index.xml sitemap index that references each file
+// by its public URL. Every chunk is streamed straight into its file.
+//
+// Parameters:
+//   - chunks: The links of each sitemap file, as produced by parse.ChunkBySize
+//   - encode: Writes one chunk as a complete XML sitemap document
+//   - prefix: Path prefix for the generated files, optionally including a directory
+//   - publicBase: Absolute base URL under which the generated files will be served
+//   - compress: Whether to gzip every file, adding a .gz suffix to the sitemap file names
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing the files
+func writeSplitSitemaps(chunks [][]parse.Link, encode func([]parse.Link, io.Writer) error, prefix, publicBase string, compress bool) error {
+	// Index entries must be absolute, so the public location of the files is mandatory
+	if publicBase == "" {
+		return fmt.Errorf("the sitemap exceeds the per-file limits of %d URLs or %d bytes: -public-base is required to build the sitemap index",
+			parse.MaxURLsPerSitemap, parse.MaxSitemapBytes)
+	}
+
+	var files []sitemapFile
+	for i, chunk := range chunks {
+		files = append(files, sitemapFile{fmt.Sprintf("%s-%d.xml", prefix, i+1), chunk})
+	}
+	return writeSitemapSet(files, encode, prefix, publicBase, compress)
+}
+
+// writeSectionSitemaps writes one sitemap file per top-level path section
+// (<prefix>-blog.xml, <prefix>-docs.xml, ...) plus a <prefix>_index.xml sitemap index
+// referencing all of them. A section above the per-file limits is numbered further
+// (<prefix>-blog-1.xml, <prefix>-blog-2.xml, ...).
+//
+// Parameters:
+//   - links: All links to write
+//   - encode: Writes one file's links as a complete XML sitemap document
+//   - prefix: Path prefix for the generated files, optionally including a directory
+//   - publicBase: Absolute base URL under which the generated files will be served
+//   - compress: Whether to gzip every file, adding a .gz suffix to the sitemap file names
+//
+// Returns:
+//   - error: Any error that occurred while splitting, encoding or writing the files
+func writeSectionSitemaps(links []parse.Link, encode func([]parse.Link, io.Writer) error, prefix, publicBase string, compress bool) error {
+	var files []sitemapFile
+	for _, section := range parse.GroupBySection(links) {
+		chunks, err := parse.ChunkBySize(section.Links, parse.MaxURLsPerSitemap, parse.MaxSitemapBytes)
+		if err != nil {
+			return fmt.Errorf("splitting section %s: %w", section.Name, err)
+		}
+		if len(chunks) == 1 {
+			files = append(files, sitemapFile{fmt.Sprintf("%s-%s.xml", prefix, section.Name), chunks[0]})
+			continue
+		}
+		for i, chunk := range chunks {
+			files = append(files, sitemapFile{fmt.Sprintf("%s-%s-%d.xml", prefix, section.Name, i+1), chunk})
+		}
+	}
+	return writeSitemapSet(files, encode, prefix, publicBase, compress)
+}
+
+// writeSitemapSet writes every file of a sitemap set plus a <prefix>_index.xml sitemap
+// index that references each file by its public URL. Every file is streamed straight
+// from its links.
+//
+// Parameters:
+//   - files: The sitemap files to write
+//   - encode: Writes one file's links as a complete XML sitemap document
+//   - prefix: Path prefix of the sitemap index
+//   - publicBase: Absolute base URL under which the generated files will be served
+//   - compress: Whether to gzip every file, adding a .gz suffix to the sitemap file names
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing the files
+func writeSitemapSet(files []sitemapFile, encode func([]parse.Link, io.Writer) error, prefix, publicBase string, compress bool) error {
+	// Index entries must be absolute, so the public location of the files is mandatory
+	if u, err := url.Parse(publicBase); err != nil || !u.IsAbs() {
+		return fmt.Errorf("-public-base %q must be an absolute URL", publicBase)
+	}
+
+	var locations []string
+	for _, file := range files {
+		path := file.path
+		write := func(w io.Writer) error {
+			return encode(file.links, w)
+		}
+		if compress {
+			path += ".gz"
+			write = gzipped(write)
+		}
+		if err := writeFileAtomic(path, write); err != nil {
+			return err
+		}
+
+		loc, err := url.JoinPath(publicBase, filepath.Base(path))
+		if err != nil {
+			return fmt.Errorf("building public URL for %s: %w", path, err)
+		}
+		locations = append(locations, loc)
+		fmt.Fprintln(info, "Sitemap written to", path)
+	}
+
+	indexXML, err := parse.EncodeSitemapIndex(locations)
+	if err != nil {
+		return err
+	}
+
+	indexPath := prefix + "_index.xml"
+	if err := writeFileAtomic(indexPath, writeString(indexXML+"\n")); err != nil {
+		return err
+	}
+	fmt.Fprintln(info, "Sitemap index written to", indexPath)
+	return nil
+}
+
+// xmlDocumentWriter returns an encoder that writes links as an XML sitemap nested with
+// indent, followed by a trailing newline so the file ends cleanly when printed to a
+// terminal or concatenated. An empty indent selects compact output, and extended adds
+// the page titles as comments.
+func xmlDocumentWriter(indent string, extended bool) func([]parse.Link, io.Writer) error {
+	write := parse.WriteXMLIndent
+	if extended {
+		write = parse.WriteXMLExtended
+	}
+	return func(links []parse.Link, w io.Writer) error {
+		if err := write(links, w, indent); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+}
+
+// writeString returns a write function that emits s unchanged.
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// gzipped wraps a write function so that everything it writes is gzip-compressed
+// on the fly. The compressor is closed after write returns so the gzip footer is
+// always flushed to the underlying writer.
+func gzipped(write func(io.Writer) error) func(io.Writer) error {
+	return func(w io.Writer) error {
+		gz := gzip.NewWriter(w)
+		if err := write(gz); err != nil {
+			gz.Close()
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("finishing gzip stream: %w", err)
+		}
+		return nil
+	}
+}
+
+// depthHistogramWidth is the length of the bar drawn for the most populated depth by
+// printDepthHistogram.
+const depthHistogramWidth = 40
+
+// printDepthHistogram writes the number of links per crawl depth to w as a text
+// histogram, one line per depth with a bar scaled to the most populated depth.
+//
+// Parameters:
+//   - w: Destination for the histogram, typically os.Stderr
+//   - links: Links to count, with their Depth set
+//
+// Returns:
+//   - error: Any error that occurred while writing
+func printDepthHistogram(w io.Writer, links []parse.Link) error {
+	counts := parse.CountByDepth(links)
+	largest := 0
+	for _, dc := range counts {
+		largest = max(largest, dc.Count)
+	}
+
+	if _, err := fmt.Fprintln(w, "URLs per depth:"); err != nil {
+		return err
+	}
+	for _, dc := range counts {
+		bar := strings.Repeat("#", max(dc.Count*depthHistogramWidth/largest, 1))
+		if _, err := fmt.Fprintf(w, "  %3d %7d %s\n", dc.Depth, dc.Count, bar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printBrokenLinks checks every link with parse.ValidateLinks and prints the broken ones
+// to stderr, one per line with their status code (or ERR if they could not be reached),
+// followed by a summary line.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the checks
+//   - links: Links to check
+//   - client: HTTP client for making requests
+//   - opts: Request settings and worker pool size, as used for the crawl
+//
+// Returns:
+//   - error: ctx.Err() if the checks were cancelled
+func printBrokenLinks(ctx context.Context, links []parse.Link, client *http.Client, opts parse.CrawlOptions) error {
+	statuses, err := parse.ValidateLinks(ctx, links, client, opts)
+	if err != nil {
+		return err
+	}
+
+	broken := 0
+	for _, status := range statuses {
+		if !status.Broken() {
+			continue
+		}
+		broken++
+		if status.StatusCode == 0 {
+			fmt.Fprintf(os.Stderr, "ERR %s: %s\n", status.Href, status.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "%d %s\n", status.StatusCode, status.Href)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d URLs broken\n", broken, len(statuses))
+	return nil
+}
+
+// printDiff compares the crawled links with the sitemap stored at path and prints the
+// URLs that were added or removed to stderr, one per line prefixed with "+" or "-",
+// in sorted order.
+//
+// Parameters:
+//   - path: Previously generated sitemap, gzip-compressed if its name ends in .gz
+//   - links: Links discovered by the current crawl
+//
+// Returns:
+//   - bool: true if any URL was added or removed
+//   - error: Any error that occurred while reading or parsing the old sitemap
+func printDiff(path string, links []parse.Link) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	read := parse.ReadXML
+	if strings.HasSuffix(path, ".gz") {
+		read = parse.ReadXMLGzip
+	}
+	oldURLs, err := read(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	current := make([]parse.Url, len(links))
+	for i, link := range links {
+		current[i] = parse.Url{Loc: link.Href}
+	}
+
+	added, removed := parse.DiffSitemaps(oldURLs, current)
+	for _, u := range added {
+		fmt.Fprintln(os.Stderr, "+", u.Loc)
+	}
+	for _, u := range removed {
+		fmt.Fprintln(os.Stderr, "-", u.Loc)
+	}
+	fmt.Fprintf(os.Stderr, "%d added, %d removed compared with %s\n", len(added), len(removed), path)
+
+	return len(added) > 0 || len(removed) > 0, nil
+}
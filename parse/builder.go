@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// SitemapBuilder is a reusable crawler for library consumers. It keeps the crawl settings
+// and HTTP client across calls and accumulates the links of every crawl, so several sites
+// can be crawled one after the other, into one sitemap or, with Reset in between, into one
+// sitemap each. A SitemapBuilder is not safe for concurrent use.
+type SitemapBuilder struct {
+	// MaxDepth is the maximum depth of every Crawl, as for CrawlBFS.
+	MaxDepth int
+
+	opts   CrawlOptions
+	client *http.Client
+	links  []Link              // Links of all crawls since the last Reset, in crawl order
+	seen   map[string]struct{} // Normalized URLs of links, so a page is listed once across crawls
+}
+
+// NewSitemapBuilder creates a SitemapBuilder crawling DefaultMaxDepth levels deep with the
+// given settings. Its HTTP client is built once with NewHTTPClient and reused by every
+// crawl, so connections are kept alive between them.
+//
+// Parameters:
+//   - options: Crawl settings applied to every crawl
+//
+// Returns:
+//   - *SitemapBuilder: A builder without any links yet
+func NewSitemapBuilder(options CrawlOptions) *SitemapBuilder {
+	return &SitemapBuilder{
+		MaxDepth: DefaultMaxDepth,
+		opts:     options,
+		client:   NewHTTPClient(options),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// Crawl crawls the site starting at startURL with CrawlBFS and adds the discovered links
+// to those of earlier crawls. Pages that were already listed are not added twice. Links
+// found before a cancellation or another error are kept.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the crawl
+//   - startURL: Absolute URL the crawl starts from
+//
+// Returns:
+//   - error: Any error that prevented the crawl from starting or completing
+func (b *SitemapBuilder) Crawl(ctx context.Context, startURL string) error {
+	result, err := CrawlBFS(ctx, []Link{{Href: startURL}}, WithOptions(b.opts), WithMaxDepth(b.MaxDepth), WithHTTPClient(b.client))
+	if result != nil {
+		for _, link := range result.Links {
+			key := normalizedKey(link.Href)
+			if _, ok := b.seen[key]; ok {
+				continue
+			}
+			b.seen[key] = struct{}{}
+			b.links = append(b.links, link)
+		}
+	}
+	return err
+}
+
+// Links returns the links accumulated since the builder was created or last Reset, in
+// crawl order. The returned slice is a copy and may be modified freely.
+func (b *SitemapBuilder) Links() []Link {
+	return slices.Clone(b.links)
+}
+
+// WriteXML writes the accumulated links to w as an XML sitemap, as the package-level
+// WriteXML does.
+//
+// Parameters:
+//   - w: Destination for the encoded sitemap
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func (b *SitemapBuilder) WriteXML(w io.Writer) error {
+	return WriteXML(b.links, w)
+}
+
+// Reset discards the accumulated links so the next Crawl starts a new sitemap. The
+// settings and the HTTP client are kept.
+func (b *SitemapBuilder) Reset() {
+	b.links = nil
+	clear(b.seen)
+}
@@ -0,0 +1,174 @@
+package parse
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// pageCacheFile is the name of the file PageCache keeps in its directory.
+const pageCacheFile = "pages.gob"
+
+// PageCache remembers the pages of a crawl between runs, so that a recrawl can ask the
+// server whether each page changed, with If-None-Match and If-Modified-Since, and reuse
+// what it learned from a page answering 304 Not Modified instead of downloading and
+// parsing it again. The page is still listed with its previous lastmod, and the links it
+// had still take part in the crawl. Pass it in CrawlOptions.Cache.
+//
+// Only the pages fetched by the latest run are kept, so pages that disappeared from the
+// site do not accumulate. A cache saved by a crawl of other seeds, or with settings that
+// change what is read from a page such as CrawlOptions.Images, is not used. A PageCache
+// is safe for concurrent use.
+type PageCache struct {
+	path string // File the cache is loaded from and saved to
+
+	mu       sync.Mutex
+	settings string                // Crawl the pages were read by, see use
+	entries  map[string]cacheEntry // Pages of the previous run, keyed by URL
+	fetched  map[string]cacheEntry // Pages of this run, saved by Save
+}
+
+// pageCacheData is the on-disk form of a PageCache.
+type pageCacheData struct {
+	Settings string                // Crawl the pages were read by
+	Pages    map[string]cacheEntry // Pages keyed by URL
+}
+
+// cacheEntry is what PageCache keeps of a single page.
+type cacheEntry struct {
+	ETag         string    // ETag header of the last full response
+	LastModified string    // Last-Modified header of the last full response, as sent
+	LastMod      string    // lastmod listed for the page
+	ContentType  string    // Content-Type header of the last full response
+	Facts        pageFacts // What the page contained
+}
+
+// LoadPageCache opens the cache kept in dir, creating the directory if needed. A
+// directory without a cache yields an empty one, so the first run fetches every page.
+//
+// Parameters:
+//   - dir: Directory holding the cache between runs
+//
+// Returns:
+//   - *PageCache: The loaded cache
+//   - error: The directory cannot be created, or the cache file cannot be read
+func LoadPageCache(dir string) (*PageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	pc := &PageCache{
+		path:    filepath.Join(dir, pageCacheFile),
+		entries: make(map[string]cacheEntry),
+		fetched: make(map[string]cacheEntry),
+	}
+
+	f, err := os.Open(pc.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening cache: %w", err)
+	}
+	defer f.Close()
+	var data pageCacheData
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("reading cache %s: %w", pc.path, err)
+	}
+	pc.settings = data.Settings
+	if data.Pages != nil {
+		pc.entries = data.Pages
+	}
+	return pc, nil
+}
+
+// Len returns the number of pages loaded from the previous run.
+func (pc *PageCache) Len() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return len(pc.entries)
+}
+
+// Save writes the pages fetched since the cache was loaded to its directory, replacing
+// the previous cache atomically.
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing the cache
+func (pc *PageCache) Save() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	// Write next to the destination so the rename stays on one filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(pc.path), "."+pageCacheFile+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating cache: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if err := gob.NewEncoder(tmp).Encode(pageCacheData{Settings: pc.settings, Pages: pc.fetched}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cache %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing cache %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), pc.path); err != nil {
+		return fmt.Errorf("saving cache %s: %w", pc.path, err)
+	}
+	return nil
+}
+
+// use prepares the cache for a crawl described by settings, dropping the pages of the
+// previous run if they were read by a crawl with other settings.
+func (pc *PageCache) use(settings string) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.settings != settings && len(pc.entries) > 0 {
+		currentLogger().Warn("ignoring cache of a crawl with other seeds or settings", "path", pc.path)
+		pc.entries = make(map[string]cacheEntry)
+	}
+	pc.settings = settings
+}
+
+// lookup returns the entry of rawURL from the previous run. A nil cache has no entries.
+func (pc *PageCache) lookup(rawURL string) (cacheEntry, bool) {
+	if pc == nil {
+		return cacheEntry{}, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	entry, ok := pc.entries[rawURL]
+	return entry, ok
+}
+
+// store records the entry of rawURL for the next run. A nil cache ignores it, as do
+// entries without a validator the server could check.
+func (pc *PageCache) store(rawURL string, entry cacheEntry) {
+	if pc == nil || (entry.ETag == "" && entry.LastModified == "") {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.fetched[rawURL] = entry
+}
+
+// setConditional asks the server to answer 304 Not Modified if the page at rawURL did
+// not change since it was cached.
+func (pc *PageCache) setConditional(req *http.Request, rawURL string) {
+	entry, ok := pc.lookup(rawURL)
+	if !ok {
+		return
+	}
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
@@ -0,0 +1,44 @@
+package parse
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ExtractCanonical scans the document's <head> for a <link rel="canonical" href="...">
+// declaration and returns its href as written. Only the first canonical link counts.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//
+// Returns:
+//   - string: The canonical URL, possibly relative to the page
+//   - bool: true if the page declares a non-empty canonical URL
+func ExtractCanonical(n *html.Node) (string, bool) {
+	head := findElement(n, atom.Head)
+	if head == nil {
+		return "", false
+	}
+
+	var canonical string
+	var walk func(*html.Node) bool
+	walk = func(node *html.Node) bool {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Link && hasRel(node, "canonical") {
+			canonical = strings.TrimSpace(attrValue(node, "href"))
+			return true
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !walk(head) || canonical == "" {
+		return "", false
+	}
+	return canonical, true
+}
@@ -0,0 +1,128 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// checkpoint is the state of an unfinished CrawlBFS, saved to CrawlOptions.CheckpointFile
+// before every level so that an interrupted crawl can be resumed with CrawlOptions.Resume.
+type checkpoint struct {
+	Seeds   []string         `json:"seeds"`   // Normalized URLs of the seeds, sorted
+	Visited []string         `json:"visited"` // Normalized URLs already enqueued, sorted
+	Queue   []checkpointNode `json:"queue"`   // The level still to be crawled
+	Results []Link           `json:"results"` // Links listed by the levels already crawled
+}
+
+// checkpointNode is the JSON form of a queued node.
+type checkpointNode struct {
+	Link  Link `json:"link"`  // The queued link
+	Depth int  `json:"depth"` // Crawl depth of the link
+}
+
+// saveCheckpoint writes the crawl state to opts.CheckpointFile, if set, before queue is
+// crawled. The file is replaced atomically, so an interruption while writing leaves the
+// previous checkpoint intact.
+func (c *crawler) saveCheckpoint(queue []node, result *CrawlResult) error {
+	path := c.opts.CheckpointFile
+	if path == "" {
+		return nil
+	}
+
+	c.visited.mu.RLock()
+	cp := checkpoint{
+		Seeds:   c.seeds,
+		Visited: slices.Sorted(maps.Keys(c.visited.urls)),
+		Queue:   make([]checkpointNode, len(queue)),
+		Results: result.Links,
+	}
+	c.visited.mu.RUnlock()
+	for i, n := range queue {
+		cp.Queue[i] = checkpointNode{n.link, n.depth}
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+
+	// Write next to the destination so the rename stays on one filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing checkpoint %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing checkpoint %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("saving checkpoint %s: %w", path, err)
+	}
+	return nil
+}
+
+// resume restores the state saved by saveCheckpoint when opts.Resume is set and the
+// checkpoint file exists, returning the level to crawl next and its depth. ok is false
+// when there is nothing to resume and the crawl starts from its seeds, as it does when
+// the checkpoint belongs to a crawl with other seeds.
+func (c *crawler) resume(result *CrawlResult) (level []node, depth int, ok bool, err error) {
+	if !c.opts.Resume || c.opts.CheckpointFile == "" {
+		return nil, 0, false, nil
+	}
+	data, err := os.ReadFile(c.opts.CheckpointFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, 0, false, fmt.Errorf("parsing checkpoint %s: %w", c.opts.CheckpointFile, err)
+	}
+	if !slices.Equal(cp.Seeds, c.seeds) {
+		currentLogger().Warn("ignoring checkpoint of a crawl with other start URLs", "checkpoint", c.opts.CheckpointFile)
+		return nil, 0, false, nil
+	}
+
+	// The saved keys are already normalized, and replace the seeds marked by newCrawler
+	c.visited.urls = make(map[string]struct{}, len(cp.Visited))
+	for _, key := range cp.Visited {
+		c.visited.urls[key] = struct{}{}
+	}
+	for _, link := range cp.Results {
+		c.list(result, link)
+		result.MaxDepthReached = max(result.MaxDepthReached, link.Depth)
+	}
+	for _, n := range cp.Queue {
+		level = append(level, node{n.Link, n.Depth})
+	}
+	if len(level) > 0 {
+		depth = level[0].depth
+	}
+	result.Resumed = true
+	currentLogger().Info("resuming crawl", "checkpoint", c.opts.CheckpointFile, "depth", depth, "queued", len(level), "listed", len(result.Links))
+	return level, depth, true, nil
+}
+
+// removeCheckpoint deletes opts.CheckpointFile once the crawl has finished, so that a
+// later run does not resume a crawl that is already complete.
+func (c *crawler) removeCheckpoint() error {
+	if c.opts.CheckpointFile == "" {
+		return nil
+	}
+	if err := os.Remove(c.opts.CheckpointFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing checkpoint: %w", err)
+	}
+	return nil
+}
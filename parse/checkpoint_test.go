@@ -0,0 +1,59 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResumedCrawlMatchesUninterruptedCrawl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first run is interrupted while it crawls depth 1
+		if r.URL.Path == "/b" {
+			cancel()
+		}
+		treeSite.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	seeds := []Link{{Href: srv.URL + "/"}}
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	opts := CrawlOptions{CheckpointFile: path, Resume: true}
+
+	partial, err := CrawlBFS(ctx, seeds, WithOptions(opts))
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("first run error = %v, want it interrupted", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("no checkpoint after the interruption: %v", err)
+	}
+
+	resumed, err := CrawlBFS(context.Background(), seeds, WithOptions(opts))
+	if err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	if !resumed.Resumed {
+		t.Error("the second run did not resume the checkpoint")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint left behind by a finished crawl: %v", err)
+	}
+
+	complete, err := CrawlBFS(context.Background(), seeds)
+	if err != nil {
+		t.Fatalf("uninterrupted run: %v", err)
+	}
+	if !reflect.DeepEqual(resumed.Links, complete.Links) {
+		t.Errorf("resumed crawl listed %+v, uninterrupted crawl %+v", resumed.Links, complete.Links)
+	}
+	if partial.PagesVisited+resumed.PagesVisited != complete.PagesVisited {
+		t.Errorf("fetched %d pages before and %d after the interruption, want %d in all",
+			partial.PagesVisited, resumed.PagesVisited, complete.PagesVisited)
+	}
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the overall time limit for a single request, including reading the
+// response body, used when CrawlOptions.Timeout is zero.
+const DefaultTimeout = 10 * time.Second
+
+// NewHTTPClient builds the HTTP client used for crawling from the timeout settings in opts.
+// The transport is a copy of http.DefaultTransport, so connection pooling keeps working;
+// only the configured timeouts and proxy are changed.
+//
+// Parameters:
+//   - opts: Crawl settings; Timeout, DialTimeout, ResponseHeaderTimeout and ProxyURL are used
+//
+// Returns:
+//   - *http.Client: A client ready to be passed to CrawlBFS or FetchAndParse
+func NewHTTPClient(opts CrawlOptions) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Route traffic through an explicit proxy, falling back to HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY from the environment
+	transport.Proxy = http.ProxyFromEnvironment
+	if opts.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(opts.ProxyURL)
+	}
+
+	// Bound connection setup separately from the overall request timeout
+	if opts.DialTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
+// BasicAuthConfig holds the credentials of CrawlOptions.BasicAuth.
+type BasicAuthConfig struct {
+	Username string // User name sent with every request
+	Password string // Password sent with every request, possibly empty
+}
+
+// ParseBasicAuth parses credentials written as user:password. Only the first colon
+// separates the two, so the password may contain colons itself.
+//
+// Parameters:
+//   - s: The credentials, such as the value of -auth or SITEMAP_AUTH
+//
+// Returns:
+//   - *BasicAuthConfig: The parsed credentials
+//   - error: s has no colon or an empty user name
+func ParseBasicAuth(s string) (*BasicAuthConfig, error) {
+	username, password, ok := strings.Cut(s, ":")
+	if !ok || username == "" {
+		return nil, fmt.Errorf("invalid credentials (expected user:password)")
+	}
+	return &BasicAuthConfig{Username: username, Password: password}, nil
+}
+
+// NewCookieJar creates a cookie jar pre-populated with cookies for the site at baseURL.
+// Attached to an http.Client, the jar sends the cookies with every request to that site
+// and keeps any updates the server makes to them, so sessions stay valid during long
+// crawls.
+//
+// Parameters:
+//   - baseURL: URL of the site the cookies belong to
+//   - cookies: Pre-existing session cookies, e.g. copied from a logged-in browser
+//
+// Returns:
+//   - http.CookieJar: The populated jar
+//   - error: An invalid baseURL
+func NewCookieJar(baseURL string, cookies []*http.Cookie) (http.CookieJar, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cookie URL %s: %w", baseURL, err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %w", err)
+	}
+	jar.SetCookies(u, cookies)
+	return jar, nil
+}
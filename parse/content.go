@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrNotHTML is wrapped by the error FetchAndParse returns for a response that is not an
+// HTML document, such as a PDF, an image or an archive, which is then not parsed.
+var ErrNotHTML = errors.New("not an HTML document")
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// NonHTMLPage describes a fetched URL that was recorded without being parsed because it
+// is not an HTML document.
+type NonHTMLPage struct {
+	URL         string // The fetched URL
+	ContentType string // Content-Type of the response, sniffed if the server sent none
+	Size        int64  // Content-Length of the response, -1 if unknown
+}
+
+// isHTML reports whether contentType denotes a document the crawler parses for links:
+// text/html or application/xhtml+xml, whatever their parameters.
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Tolerate malformed parameters as long as the type itself is readable
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// sniffContentType guesses the type of a response without a Content-Type header from its
+// first bytes, which remain available to read from body.
+func sniffContentType(body *bufio.Reader) string {
+	head, _ := body.Peek(sniffLen) // A shorter body is sniffed as it is
+	return http.DetectContentType(head)
+}
@@ -0,0 +1,1226 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/time/rate"
+)
+
+// CrawlOptions configures the behavior of CrawlBFS and FetchAndParse beyond the required
+// parameters; crawls take it through WithOptions or the more specific CrawlOption
+// constructors. The zero value is valid and reproduces the original sequential crawl.
+type CrawlOptions struct {
+	// UserAgent is sent with every request. Empty selects DefaultUserAgent.
+	UserAgent string
+
+	// Concurrency is the number of pages fetched in parallel. Values below 1 are treated as 1.
+	Concurrency int
+
+	// Robots, when non-nil, is consulted before any URL is enqueued; disallowed URLs
+	// are neither fetched nor included in the results.
+	Robots *RobotsFilter
+
+	// Delay is the minimum pause between consecutive fetches made by each worker,
+	// retries included. It applies per worker, so total throughput still scales with Concurrency.
+	// A larger Crawl-delay from Robots takes precedence. Zero disables the delay.
+	Delay time.Duration
+
+	// MaxCrawlDelay caps the Crawl-delay taken from Robots, so a hostile robots.txt cannot
+	// stall the crawl. It does not limit Delay. Zero means no cap.
+	MaxCrawlDelay time.Duration
+
+	// Limiter, when non-nil, is consulted before every fetch, retries included, with the
+	// host of the URL. It applies across workers, unlike Delay; see PerHostLimiter.
+	Limiter Limiter
+
+	// Images enables collection of each fetched page's <img> sources into Link.Images
+	// for the image sitemap extension.
+	Images bool
+
+	// Videos enables collection of each fetched page's <video> elements into Link.Videos
+	// for the video sitemap extension.
+	Videos bool
+
+	// Hreflang enables collection of each fetched page's hreflang alternates into
+	// Link.Alternates, emitted as <xhtml:link> elements.
+	Hreflang bool
+
+	// ExcludePatterns lists regular expressions matched against every discovered URL.
+	// Matching URLs are neither crawled nor included in the results.
+	ExcludePatterns []*regexp.Regexp
+
+	// IncludePatterns, when non-empty, restricts the crawl to URLs whose path matches at
+	// least one of these regular expressions. Exclude patterns are checked first and win.
+	IncludePatterns []*regexp.Regexp
+
+	// StripQuery removes the query string of every discovered URL before it is checked
+	// against the visited URLs, queued and listed, keeping only KeepQueryParams; see the
+	// StripQuery function.
+	StripQuery bool
+
+	// KeepQueryParams names the query parameters StripQuery leaves in place, for pages
+	// whose content depends on them such as paginated listings.
+	KeepQueryParams []string
+
+	// TrailingSlash spells every discovered URL with or without a trailing slash, using
+	// the policies of ApplyTrailingSlash. Empty selects TrailingSlashKeep, where a page
+	// that redirects to its other spelling is listed under the redirect target.
+	TrailingSlash string
+
+	// PreferHTTPS treats http:// and https:// links to the host of an https seed as the
+	// same page, fetching and listing only the https form. If that form cannot be reached
+	// at all, the page is fetched over http instead and noted in Report.InsecureFallbacks.
+	PreferHTTPS bool
+
+	// StrictScheme only treats links as internal when their scheme is the scheme of the
+	// page they are on, so an http:// link on an https page is external, as for
+	// IsInternalLink. Otherwise http and https links to the same host are both internal.
+	StrictScheme bool
+
+	// HTTPSOnly crawls and lists https:// URLs only: http:// seeds and links are upgraded
+	// to https:// with a warning and then checked like any other link, and a page is
+	// never fetched over http, even when PreferHTTPS would fall back to it.
+	HTTPSOnly bool
+
+	// IncludeSubdomains treats links to the registrable domain of a seed, such as
+	// example.com for www.example.com, and to any of its subdomains as internal, so that
+	// docs.example.com is crawled along with www.example.com. Without a Limiter, requests
+	// to every host are then spaced by the politeness delay across all workers.
+	IncludeSubdomains bool
+
+	// LinkFilter, when non-nil, is called with every discovered URL that passed the patterns;
+	// URLs for which it returns false are neither crawled nor included in the results.
+	LinkFilter func(rawURL string) bool
+
+	// Metrics, when non-nil, receives the number of queued pages, the depth being crawled
+	// and the duration and outcome of every fetch while the crawl runs.
+	Metrics Metrics
+
+	// Graph, when non-nil, receives every listed page and every internal link between
+	// crawled pages, for visualizing the site structure.
+	Graph *Graph
+
+	// Report, when non-nil, receives a summary of the crawl: listed URLs per depth,
+	// failed fetches and fetch durations.
+	Report *Report
+
+	// MaxRetries is the number of times a page that fails with a transient error
+	// (network error, 429 or 5xx) is retried. Zero disables retries.
+	MaxRetries int
+
+	// RetryDelay is the initial backoff between retries, doubled on each attempt.
+	// Zero selects a default of 500ms.
+	RetryDelay time.Duration
+
+	// Timeout is the overall time limit for each request made by a client from
+	// NewHTTPClient. Zero selects DefaultTimeout.
+	Timeout time.Duration
+
+	// DialTimeout limits how long establishing a connection may take. Zero keeps the
+	// default of http.DefaultTransport.
+	DialTimeout time.Duration
+
+	// ResponseHeaderTimeout limits how long to wait for the response headers once the
+	// request has been sent. Zero means no limit beyond Timeout.
+	ResponseHeaderTimeout time.Duration
+
+	// ProxyURL, when non-nil, routes every request of a client from NewHTTPClient
+	// through this HTTP, HTTPS or SOCKS5 proxy. Nil uses HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY from the environment.
+	ProxyURL *url.URL
+
+	// Cookies are sent with every request, typically to carry an existing login
+	// session. Logging in itself, such as submitting a form, is not supported.
+	Cookies []*http.Cookie
+
+	// Headers are set on every request, e.g. Accept-Language or an API key. Host and
+	// Content-Length are rejected, and User-Agent is always taken from UserAgent.
+	Headers map[string]string
+
+	// BasicAuth, when non-nil, sends HTTP Basic Auth credentials with every request, for
+	// sites behind a password prompt. They are only encoded, not encrypted, so crawl such
+	// sites over https, ideally with HTTPSOnly.
+	BasicAuth *BasicAuthConfig
+
+	// ProgressWriter, when non-nil, receives a line before every fetch naming the page
+	// and the number of queued and completed fetches. Nil disables progress output.
+	ProgressWriter io.Writer
+
+	// MaxPages caps the number of pages fetched during the crawl. Once the budget is spent no
+	// further pages are fetched and CrawlResult.Truncated is set. Zero means no limit.
+	MaxPages int
+
+	// IncludeUnfetched lists the URLs that were discovered but left unfetched because
+	// MaxPages was reached. They are left out in Verify mode, where nothing is listed unchecked.
+	IncludeUnfetched bool
+
+	// CheckpointFile, when set, is where CrawlBFS saves its queue, visited URLs and results
+	// as JSON before every level, so an interrupted crawl can be resumed. The file is
+	// removed once the crawl finishes. CrawlDFS ignores it.
+	CheckpointFile string
+
+	// Resume continues the crawl saved in CheckpointFile, if that file exists and was
+	// saved by a crawl with the same seeds, instead of starting from the seeds. The seeds
+	// are still validated but not crawled again. A checkpoint of other seeds is replaced.
+	Resume bool
+
+	// SkipNonHTML leaves out pages that are not HTML documents, such as PDFs and images,
+	// which are otherwise listed. They are never parsed for links either way, and are
+	// recorded in CrawlResult.NonHTML. A page without a Content-Type is judged by its
+	// first bytes.
+	SkipNonHTML bool
+
+	// IncludeNoindex lists pages marked noindex by a robots meta tag or X-Robots-Tag header
+	// instead of leaving them out.
+	IncludeNoindex bool
+
+	// IgnoreNofollow follows the links of pages marked nofollow by a robots meta tag or
+	// X-Robots-Tag header, e.g. on a staging site that is nofollow throughout.
+	IgnoreNofollow bool
+
+	// IgnoreCanonical lists every page under the URL it was fetched from, disregarding
+	// its <link rel="canonical">.
+	IgnoreCanonical bool
+
+	// Cache, when non-nil, makes a recrawl ask the server whether each page changed since
+	// the previous run with If-None-Match and If-Modified-Since. A page answering 304 Not
+	// Modified keeps its previous lastmod and links without being parsed again. Save the
+	// cache once the crawl completes; see PageCache.
+	Cache *PageCache
+
+	// Verify lists only URLs confirmed to return 200 without redirecting. Pages at the
+	// depth limit, which are otherwise listed unfetched, are checked with a HEAD request
+	// (falling back to GET) using the same workers and delays as the crawl.
+	Verify bool
+}
+
+// node represents a link with its depth in the crawl tree.
+type node struct {
+	link  Link // The link being processed
+	depth int  // How many levels deep this link is from the starting point
+}
+
+// pageResult carries the outcome of crawling a single node back from a worker.
+type pageResult struct {
+	index     int           // Position of the node within its BFS level
+	link      Link          // The crawled link, enriched with response metadata
+	neighbors []Link        // Internal links found on the page that were unvisited at fetch time
+	links     []string      // Every followable internal link on the page, recorded only for a Graph
+	fetchErr  error         // Why the page could not be fetched, nil on success
+	duration  time.Duration // Time spent fetching the page, zero if it was not fetched
+	skipped   bool          // The page was not fetched because the depth limit was reached
+	abandoned bool          // The page was given up before its fetch started; fetchErr says why
+	canonical string        // Same-host canonical URL replacing the fetched one, if it differs
+	omit      bool          // The page is noindex or canonicalized off-site and must not be listed
+	noindex   bool          // The page was left out because it is marked noindex
+	insecure  bool          // The page was fetched over http after its https form failed
+	refresh   Link          // Internal target of the page's meta refresh, if any
+	unchanged bool          // The page answered 304 Not Modified and was taken from the cache
+	nonHTML   bool          // The page is not an HTML document and was not parsed
+	size      int64         // Content-Length of a non-HTML page, -1 if unknown
+}
+
+// pageFacts is what the crawl reads from a fetched page, independently of the pages
+// visited so far, so that it can be kept in a PageCache for the next run.
+type pageFacts struct {
+	Title             string         // Content of the <title> element
+	Description       string         // Content of the meta description
+	Noindex           bool           // The page is marked noindex by a robots meta tag or X-Robots-Tag header
+	Nofollow          bool           // The page is marked nofollow by a robots meta tag or X-Robots-Tag header
+	Canonical         string         // Canonical URL declared by the page, if it differs from the page URL
+	CanonicalSameSite bool           // The canonical URL is served from the host of the page
+	Images            []Image        // Images of the page, if CrawlOptions.Images is set
+	Videos            []Video        // Videos of the page, if CrawlOptions.Videos is set
+	Alternates        []HreflangLink // Language variants of the page, if CrawlOptions.Hreflang is set
+	Refresh           string         // Absolute target of the page's meta refresh, if any
+	Links             []Link         // Internal links of the page, nofollow or not
+}
+
+// pacer enforces a minimum interval between the fetches of a single worker.
+// It is not safe for concurrent use; each worker owns its own pacer.
+type pacer struct {
+	delay time.Duration // Minimum time between two fetches
+	last  time.Time     // When the previous fetch started
+}
+
+// wait blocks until at least delay has passed since the previous call, or until ctx is cancelled.
+func (p *pacer) wait(ctx context.Context) {
+	if p.delay <= 0 {
+		return
+	}
+	if !p.last.IsZero() {
+		if remaining := p.delay - time.Since(p.last); remaining > 0 {
+			sleep(ctx, remaining)
+		}
+	}
+	p.last = time.Now()
+}
+
+// visitedSet is a goroutine-safe set of URLs that have already been enqueued.
+// Workers consult it with a read lock to drop known links early, while the
+// coordinator performs the authoritative check-and-insert under the write lock.
+// URLs are keyed by their NormalizeURL form, so equivalent spellings of a page count
+// as the same URL while the first spelling seen is the one that gets listed.
+type visitedSet struct {
+	mu   sync.RWMutex
+	urls map[string]struct{}
+}
+
+// newVisitedSet creates an empty visitedSet.
+func newVisitedSet() *visitedSet {
+	return &visitedSet{urls: make(map[string]struct{})}
+}
+
+// contains reports whether url has already been enqueued.
+func (v *visitedSet) contains(url string) bool {
+	key := normalizedKey(url)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, ok := v.urls[key]
+	return ok
+}
+
+// add marks url as enqueued and reports whether it was newly added.
+func (v *visitedSet) add(url string) bool {
+	key := normalizedKey(url)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.urls[key]; ok {
+		return false
+	}
+	v.urls[key] = struct{}{}
+	return true
+}
+
+// crawler holds the state shared by the workers of a single CrawlBFS call.
+type crawler struct {
+	client   *http.Client    // HTTP client for making requests
+	opts     CrawlOptions    // Crawl settings supplied by the caller
+	visited  *visitedSet     // URLs already enqueued
+	pacers   []pacer         // Per-worker politeness delays; one worker per pacer
+	progress *progress       // Progress reporting, silent without a ProgressWriter
+	stream   chan Link       // Receives the listed links instead of the result when streaming
+	done     <-chan struct{} // Closed when nobody may be receiving from stream anymore; nil to always wait
+	secure   map[string]bool // Hosts of the https seeds, whose http links PreferHTTPS upgrades
+	domains  map[string]bool // Registrable domains of the seeds, whose subdomains IncludeSubdomains crawls
+	origins  map[string]bool // Scheme and host of every seed, each crawled as internal
+	seeds    []string        // Normalized URLs of the seeds, sorted, identifying the crawl in checkpoints
+
+	delayMu sync.Mutex               // Guards delays
+	delays  map[string]*rate.Limiter // Crawl-delay of every other host with its own robots.txt, by host
+}
+
+// CrawlBFS performs a breadth-first search crawl of a website starting from the provided links.
+// It systematically visits pages level by level, extracting internal links from each page
+// and adding them to the crawl queue. The crawling stops when the maximum depth is reached
+// or when all discoverable internal pages have been visited.
+//
+// The BFS approach ensures that pages closer to the starting point are crawled first,
+// which is ideal for sitemap generation as it prioritizes more important/accessible pages.
+// Pages within a level are fetched by a pool of WithConcurrency workers, and the next
+// level is only built once every page of the current level has been processed, so the
+// set and order of discovered URLs does not depend on the concurrency setting.
+//
+// Cancelling ctx stops the crawl once the current level has been fetched completely;
+// requests already under way are not aborted, so an interrupted crawl lists every page of
+// the levels it got to. Reaching the deadline of ctx instead aborts in-flight requests and
+// stops the crawl at once. Either way the links merged so far are returned with
+// CrawlResult.Err set to ctx.Err(), together with an error wrapping both ErrInterrupted
+// and ctx.Err().
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the crawl
+//   - links: Seed links to start crawling from, all enqueued at depth 0
+//   - options: Crawl settings such as WithMaxDepth (DefaultMaxDepth if omitted) and WithConcurrency
+//
+// Returns:
+//   - *CrawlResult: All unique internal links discovered during the crawl, with statistics;
+//     partial if the crawl was cancelled
+//   - error: Any error that prevented the crawl from starting or completing
+func CrawlBFS(ctx context.Context, links []Link, options ...CrawlOption) (_ *CrawlResult, err error) {
+	cfg := newCrawlConfig(options)
+	c, level, err := newCrawler(links, cfg.client, cfg.opts)
+	if err != nil {
+		return nil, err
+	}
+	finish := c.startStream(cfg.stream)
+	defer func() { err = errors.Join(err, finish()) }()
+	return c.bfs(ctx, level, cfg.maxDepth)
+}
+
+// CrawlBFSStream crawls like CrawlBFS, but sends every link on the returned channel as
+// soon as it is listed instead of collecting them, so callers can process or save the URLs
+// of very large sites incrementally. The crawl waits for the caller to receive each link,
+// which gives the caller control over backpressure.
+//
+// The link channel is closed when the crawl ends; the error channel then receives the
+// error CrawlBFS would have returned, or nil, and is closed too. Cancelling ctx stops the
+// crawl even if the caller has stopped receiving links. The crawl statistics of
+// CrawlResult are not available, and StreamTo is ignored.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the crawl
+//   - links: Seed links to start crawling from, all enqueued at depth 0
+//   - options: Crawl settings as for CrawlBFS
+//
+// Returns:
+//   - <-chan Link: The listed links, in the order CrawlBFS would list them
+//   - <-chan error: Receives the outcome of the crawl once the link channel is closed
+func CrawlBFSStream(ctx context.Context, links []Link, options ...CrawlOption) (<-chan Link, <-chan error) {
+	out := make(chan Link)
+	errc := make(chan error, 1)
+
+	cfg := newCrawlConfig(options)
+	c, level, err := newCrawler(links, cfg.client, cfg.opts)
+	if err != nil {
+		close(out)
+		errc <- err
+		close(errc)
+		return out, errc
+	}
+
+	c.stream, c.done = out, ctx.Done()
+	go func() {
+		_, err := c.bfs(ctx, level, cfg.maxDepth)
+		close(out)
+		errc <- err
+		close(errc)
+	}()
+	return out, errc
+}
+
+// bfs runs the level-by-level crawl shared by CrawlBFS and CrawlBFSStream, starting from
+// the seeds in level. Listed links go wherever list sends them.
+func (c *crawler) bfs(ctx context.Context, level []node, maxDepth int) (*CrawlResult, error) {
+	start := time.Now()
+
+	// Store all discovered links for the final sitemap, along with crawl statistics
+	result := &CrawlResult{}
+
+	// Pick up where an interrupted run left off, if asked to
+	firstDepth := 0
+	if resumed, depth, ok, err := c.resume(result); err != nil {
+		return nil, err
+	} else if ok {
+		level, firstDepth = resumed, depth
+	}
+
+	// Levels are fetched to the end even once ctx has been cancelled, but not past its deadline
+	levelCtx, cancel := levelContext(ctx)
+	defer cancel()
+
+	// Process one BFS level at a time until no new pages are discovered
+	for depth := firstDepth; len(level) > 0; depth++ {
+		// Every completed level is saved, so a resumed crawl continues with the next one
+		if err := c.saveCheckpoint(level, result); err != nil {
+			result.Duration = time.Since(start)
+			return result, err
+		}
+
+		// Stop between levels once ctx has ended
+		if err := ctx.Err(); err != nil {
+			result.Duration, result.Interrupted, result.Err = time.Since(start), true, err
+			return result, fmt.Errorf("%w at depth %d: %w", ErrInterrupted, depth, err)
+		}
+
+		// Only fetch as many pages of the level as the page budget allows, in level order
+		// so the same pages are chosen on every run
+		expand := depth < maxDepth
+		var unfetched []node
+		if remaining := c.remainingPages(result); (expand || c.opts.Verify) && remaining < len(level) {
+			level, unfetched = level[:remaining], level[remaining:]
+		}
+
+		currentLogger().Info("crawling level", "depth", depth, "pages", len(level))
+
+		// Merge results in level order so the output is deterministic, adding unvisited
+		// neighbors to the next level for future processing. Meta refresh targets belong
+		// to the level of the page redirecting to them and are crawled right after it.
+		var next []node
+		for batch := level; len(batch) > 0; {
+			var refreshed []node
+			for _, page := range c.crawlLevel(levelCtx, batch, expand) {
+				for _, neighbor := range c.merge(page, result) {
+					next = append(next, node{neighbor, depth + 1})
+				}
+				if target, ok := c.refreshTarget(page); ok {
+					refreshed = append(refreshed, node{target, depth})
+				}
+			}
+			batch = refreshed
+			if remaining := c.remainingPages(result); remaining < len(batch) {
+				batch, unfetched = batch[:remaining], append(unfetched, batch[remaining:]...)
+			}
+		}
+
+		// A level cut short by the deadline is incomplete, so the checkpoint keeps it to be repeated
+		if err := levelCtx.Err(); err != nil {
+			result.Duration, result.Interrupted, result.Err = time.Since(start), true, err
+			return result, fmt.Errorf("%w at depth %d: %w", ErrInterrupted, depth, err)
+		}
+
+		// Out of budget: whatever is known but unfetched can still be listed on request
+		if unfetched != nil {
+			c.truncate(result, append(unfetched, next...))
+			break
+		}
+		level = next
+	}
+
+	result.Duration = time.Since(start)
+	return result, c.removeCheckpoint()
+}
+
+// levelContext returns the context the pages of a BFS level are fetched with: ctx without
+// its cancellation, so that an interrupted crawl finishes the level, but with its deadline,
+// so that a time limit still aborts in-flight requests.
+func levelContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	levelCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(levelCtx, deadline)
+	}
+	return levelCtx, func() {}
+}
+
+// newCrawler validates the seeds of a crawl and prepares the state shared by its workers.
+//
+// Parameters:
+//   - links: Seed links to start crawling from
+//   - client: HTTP client for making requests; nil builds one with NewHTTPClient(opts)
+//   - opts: Crawl settings supplied by the caller
+//
+// Returns:
+//   - *crawler: The crawler, with the seeds already marked as visited
+//   - []node: The seeds at depth 0, without fragments and duplicates
+//   - error: No seeds, or only seeds disallowed by robots.txt
+func newCrawler(links []Link, client *http.Client, opts CrawlOptions) (*crawler, []node, error) {
+	// Validate input
+	if len(links) == 0 {
+		return nil, nil, fmt.Errorf("no links to traverse")
+	}
+
+	if client == nil {
+		client = NewHTTPClient(opts)
+	}
+
+	c := &crawler{
+		client:   client,
+		opts:     opts,
+		visited:  newVisitedSet(), // Track visited URLs to avoid infinite loops and duplicate processing
+		pacers:   make([]pacer, max(opts.Concurrency, 1)),
+		progress: &progress{w: opts.ProgressWriter, metrics: opts.Metrics},
+		secure:   make(map[string]bool),
+		domains:  make(map[string]bool),
+		origins:  make(map[string]bool),
+		delays:   make(map[string]*rate.Limiter),
+	}
+
+	if c.opts.RetryDelay <= 0 {
+		c.opts.RetryDelay = defaultRetryDelay
+	}
+
+	// Give each worker its own politeness delay, honoring robots.txt if it asks for more
+	delay := opts.EffectiveDelay()
+	for i := range c.pacers {
+		c.pacers[i].delay = delay
+	}
+
+	// Workers spread over several subdomains could otherwise all hit one of them at once
+	if opts.IncludeSubdomains && opts.Limiter == nil && delay > 0 {
+		c.opts.Limiter = NewPerHostLimiter(float64(time.Second)/float64(delay), 1)
+	}
+
+	// Start with every seed at depth 0, without fragments
+	var seeds []node
+	var disallowed []string
+	for _, link := range links {
+		link.Href = c.opts.cleanURL(link.Href)
+		if upgraded := c.upgrade(link.Href); upgraded != link.Href {
+			currentLogger().Warn("upgrading start URL to https", "url", link.Href)
+			link.Href = upgraded
+		}
+		if u, err := url.Parse(link.Href); err == nil {
+			if u.Scheme == "https" {
+				c.secure[u.Host] = true
+			}
+			c.domains[registrableDomain(u.Hostname())] = true
+			c.origins[u.Scheme+"://"+u.Host] = true
+		}
+		if !opts.Robots.Allowed(link.Href) {
+			currentLogger().Warn("skipping start URL disallowed by robots.txt", "url", link.Href)
+			disallowed = append(disallowed, link.Href)
+			continue
+		}
+		if c.visited.add(link.Href) {
+			seeds = append(seeds, node{link, 0})
+		}
+	}
+
+	// The other seeds can still be crawled unless robots.txt rules out every one of them
+	if len(seeds) == 0 && len(disallowed) > 0 {
+		return nil, nil, fmt.Errorf("start URL %s is disallowed by robots.txt", strings.Join(disallowed, ", "))
+	}
+	for _, n := range seeds {
+		c.seeds = append(c.seeds, normalizedKey(n.link.Href))
+	}
+	slices.Sort(c.seeds)
+	c.opts.Cache.use(c.cacheSettings())
+	return c, seeds, nil
+}
+
+// remainingPages returns how many more pages may be fetched under opts.MaxPages, or
+// math.MaxInt without a limit.
+func (c *crawler) remainingPages(result *CrawlResult) int {
+	if c.opts.MaxPages <= 0 {
+		return math.MaxInt
+	}
+	return max(c.opts.MaxPages-result.PagesVisited, 0)
+}
+
+// truncate marks result as cut short by opts.MaxPages, listing the discovered but
+// unfetched nodes if opts.IncludeUnfetched asks for it.
+func (c *crawler) truncate(result *CrawlResult, unfetched []node) {
+	result.Truncated = true
+	currentLogger().Warn("page budget exhausted", "max_pages", c.opts.MaxPages, "unfetched", len(unfetched))
+	if !c.opts.IncludeUnfetched || c.opts.Verify {
+		return
+	}
+	for _, n := range unfetched {
+		if !c.opts.wanted(n.link.Href) {
+			continue
+		}
+		n.link.Depth = n.depth
+		c.list(result, n.link)
+	}
+}
+
+// list adds a link to the sitemap: to result, or to the stream of StreamTo or
+// CrawlBFSStream, and to the optional Graph and Report. A link is dropped rather than
+// block the crawl forever once done is closed.
+func (c *crawler) list(result *CrawlResult, link Link) {
+	if c.stream != nil {
+		select {
+		case c.stream <- link:
+		case <-c.done:
+		}
+	} else {
+		result.Links = append(result.Links, link)
+	}
+	if c.opts.Graph != nil {
+		c.opts.Graph.AddNode(link.Href, link.Depth)
+	}
+	if c.opts.Report != nil {
+		c.opts.Report.addListed(link.Depth)
+	}
+}
+
+// startStream makes list send links to EncodeXMLStream writing to w, until the returned
+// function is called; it waits for the encoder to finish and returns its error. Without
+// a writer nothing is streamed.
+func (c *crawler) startStream(w io.Writer) func() error {
+	if w == nil {
+		return func() error { return nil }
+	}
+	c.stream = make(chan Link)
+	done := make(chan error, 1)
+	go func() {
+		done <- EncodeXMLStream(c.stream, w)
+	}()
+	return func() error {
+		close(c.stream)
+		return <-done
+	}
+}
+
+// merge records the outcome of a crawled page in result and in the optional Graph and
+// Report. It must only be called from the goroutine coordinating the crawl.
+//
+// Parameters:
+//   - page: The outcome of crawling one node
+//   - result: The result being built
+//
+// Returns:
+//   - []Link: The page's neighbors that were not visited yet, now marked as visited
+func (c *crawler) merge(page pageResult, result *CrawlResult) []Link {
+	// List a canonicalized page under its canonical URL, unless that URL is
+	// already known and therefore listed on its own
+	if page.canonical != "" {
+		page.link.Href = page.canonical
+		page.omit = page.omit || !c.visited.add(page.canonical)
+	}
+	if !page.omit && c.opts.wanted(page.link.Href) {
+		c.list(result, page.link)
+	}
+	result.addPage(page)
+	if c.opts.Report != nil {
+		c.opts.Report.addPage(page)
+	}
+	if c.opts.Graph != nil {
+		for _, href := range page.links {
+			c.opts.Graph.AddEdge(page.link.Href, href)
+		}
+	}
+
+	var fresh []Link
+	for _, neighbor := range page.neighbors {
+		if c.visited.add(neighbor.Href) {
+			fresh = append(fresh, neighbor)
+		}
+	}
+	return fresh
+}
+
+// refreshTarget returns the meta refresh target of a merged page if it was not visited
+// yet, now marked as visited. It must only be called from the goroutine coordinating the
+// crawl.
+func (c *crawler) refreshTarget(page pageResult) (Link, bool) {
+	if page.refresh.Href == "" || !c.visited.add(page.refresh.Href) {
+		return Link{}, false
+	}
+	return page.refresh, true
+}
+
+// crawlLevel processes every node of a single BFS level using a pool of workers, one per pacer.
+// Workers consume nodes from a shared jobs channel and send their outcome back on a
+// results channel; the results are returned indexed by the node's position in level.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the crawl
+//   - level: Nodes sharing the same depth
+//   - expand: Whether pages should be fetched to discover links (false at maximum depth)
+//
+// Returns:
+//   - []pageResult: One result per node, in the same order as level
+func (c *crawler) crawlLevel(ctx context.Context, level []node, expand bool) []pageResult {
+	type job struct {
+		index int
+		node  node
+	}
+
+	// Only pages that are expanded or verified get fetched
+	if expand || c.opts.Verify {
+		c.progress.enqueue(len(level))
+	}
+
+	jobs := make(chan job)
+	results := make(chan pageResult)
+
+	// Start the worker pool; no more goroutines than there is work for
+	var wg sync.WaitGroup
+	for i := range min(len(c.pacers), len(level)) {
+		wg.Add(1)
+		go func(p *pacer) {
+			defer wg.Done()
+			for j := range jobs {
+				page := c.crawlPage(ctx, j.node, expand, p)
+				page.index = j.index
+				results <- page
+			}
+		}(&c.pacers[i])
+	}
+
+	// Feed the level to the workers, then close results once they are all done
+	go func() {
+		for i, n := range level {
+			jobs <- job{i, n}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	pages := make([]pageResult, len(level))
+	for page := range results {
+		pages[page.index] = page
+	}
+	return pages
+}
+
+// crawlPage fetches a single page and extracts its unvisited internal links that robots.txt
+// allows. Pages that are not expanded, or that fail to fetch without a response, are still
+// returned so that they appear in the sitemap, but they contribute no neighbors. Pages
+// answering with a status other than 200, marked noindex or canonicalized to another domain
+// are flagged for exclusion, pages with a same-host canonical URL carry it for
+// substitution, and pages marked nofollow contribute no neighbors.
+// Once ctx has ended, the remaining pages are returned without being fetched.
+func (c *crawler) crawlPage(ctx context.Context, n node, expand bool, p *pacer) pageResult {
+	page := pageResult{link: n.link}
+	page.link.Depth = n.depth
+
+	// Skip further crawling if we've reached maximum depth; such pages are only
+	// checked for availability in verify mode
+	if !expand && !c.opts.Verify {
+		page.skipped = true
+		return page
+	}
+
+	// Take the page off the queue on every way out that does not get to fetch it
+	fetching := false
+	defer func() {
+		if !fetching {
+			c.progress.dropped()
+		}
+	}()
+
+	// Drain the rest of the level quickly once ctx has ended, which for CrawlBFS means its
+	// deadline has passed; unfetched pages cannot be confirmed in verify mode
+	if ctx.Err() != nil {
+		page.fetchErr, page.abandoned = ctx.Err(), true
+		page.omit = c.opts.Verify
+		return page
+	}
+	fetch := fetchPage
+	if !expand {
+		fetch = verifyPage
+	}
+
+	// Fetch and parse the current page to find more internal links. Retries wait for
+	// the worker's politeness delay and the host's rate limit too, on top of their backoff.
+	pace := func() error {
+		p.wait(ctx)
+		if err := c.waitCrawlDelay(ctx, n.link.Href); err != nil {
+			return err
+		}
+		if c.opts.Limiter == nil {
+			return nil
+		}
+		return c.opts.Limiter.Wait(ctx, hostOf(n.link.Href))
+	}
+	if err := pace(); err != nil {
+		page.fetchErr, page.abandoned = err, true
+		page.omit = c.opts.Verify
+		return page
+	}
+	fetching = true
+	c.progress.visiting(n.depth, n.link.Href)
+	currentLogger().Debug("fetching", "url", n.link.Href, "depth", n.depth, "verify_only", !expand)
+	start := time.Now()
+	retry := false
+	fetchURL := func(href string) (fetchedPage, error) {
+		return withRetry(ctx, c.opts, func() (fetchedPage, error) {
+			if retry {
+				if err := pace(); err != nil {
+					return fetchedPage{}, err
+				}
+			}
+			retry = true
+			return fetch(ctx, href, c.client, c.opts)
+		})
+	}
+	fetched, err := fetchURL(n.link.Href)
+
+	// An https page that cannot be reached at all may still be served over plain http
+	if err != nil && fetched.status == 0 && ctx.Err() == nil {
+		if fallback, ok := c.downgrade(n.link.Href); ok {
+			if insecure, insecureErr := fetchURL(fallback); insecureErr == nil {
+				currentLogger().Warn("https unreachable, fetched over http", "url", n.link.Href, "err", err)
+				fetched, err = insecure, nil
+				page.link.Href, page.insecure = fallback, true
+			}
+		}
+	}
+	page.duration = time.Since(start)
+	page.link.StatusCode = fetched.status
+	page.link.ContentType = fetched.contentType
+
+	// A PDF, image or other file is served all the same; it is only not parsed
+	if errors.Is(err, ErrNotHTML) {
+		currentLogger().Debug("not parsing non-HTML page", "url", n.link.Href, "content_type", fetched.contentType)
+		err = nil
+	}
+
+	// Count the fetch once it is known whether the page failed, as CrawlResult does
+	defer func() {
+		c.progress.finished(page.duration, page.fetchErr)
+	}()
+	if err != nil {
+		currentLogger().Warn("fetch failed", "url", n.link.Href, "status", fetched.status, "err", err)
+		page.fetchErr = err
+
+		// A page answering with an error status is not listed, nor is any page that could
+		// not be confirmed in verify mode; it still counts as a failure of the crawl
+		page.omit = c.opts.Verify || (fetched.status != 0 && fetched.status != http.StatusOK)
+		return page // Skip this page but continue crawling others
+	}
+
+	// In verify mode a redirecting URL is not listed, though its target's links still count
+	if c.opts.Verify {
+		if err := checkRedirect(fetched, n.link.Href); err != nil {
+			currentLogger().Warn("omitting redirecting page", "url", n.link.Href, "location", fetched.location)
+			page.fetchErr = err
+			page.omit = true
+		}
+	}
+
+	// A page redirecting between spellings of its URL, typically adding or removing a
+	// trailing slash, is listed under the spelling the server prefers
+	if c.opts.TrailingSlash == "" || c.opts.TrailingSlash == TrailingSlashKeep {
+		if target := withoutFragment(fetched.location); target != "" && target != n.link.Href && normalizedKey(target) == normalizedKey(n.link.Href) {
+			page.link.Href = target
+		}
+	}
+
+	// Record the page's modification time so search engines get a freshness hint
+	page.link.LastMod = lastModified(fetched.header)
+
+	// Files other than HTML have no links to follow, and are only listed if wanted
+	if fetched.contentType != "" && !isHTML(fetched.contentType) {
+		page.nonHTML, page.size = true, fetched.size
+		page.omit = page.omit || c.opts.SkipNonHTML
+		return page
+	}
+	if !expand {
+		return page
+	}
+
+	// Read the page, unless it did not change since the previous run, in which case what
+	// was read from it then still holds, lastmod included
+	var facts pageFacts
+	if entry, ok := c.opts.Cache.lookup(n.link.Href); ok && fetched.status == http.StatusNotModified {
+		currentLogger().Debug("page not modified", "url", n.link.Href)
+		page.link.LastMod, page.link.ContentType, facts = entry.LastMod, entry.ContentType, entry.Facts
+		page.unchanged = true
+		c.opts.Cache.store(n.link.Href, entry)
+	} else {
+		facts = c.readPage(fetched, n.link.Href)
+		c.opts.Cache.store(n.link.Href, cacheEntry{
+			ETag:         fetched.header.Get("ETag"),
+			LastModified: fetched.header.Get("Last-Modified"),
+			LastMod:      page.link.LastMod,
+			ContentType:  page.link.ContentType,
+			Facts:        facts,
+		})
+	}
+
+	// Keep what the page says about itself for richer output formats
+	page.link.Title = facts.Title
+	page.link.Description = facts.Description
+
+	// Honor robots directives from both the meta tag and the X-Robots-Tag header
+	page.noindex = facts.Noindex && !c.opts.IncludeNoindex
+	page.omit = page.noindex
+
+	// Record the page under its canonical URL; an off-site canonical means the page
+	// belongs in another site's sitemap
+	if canonical := facts.Canonical; canonical != "" && !c.opts.IgnoreCanonical {
+		if facts.CanonicalSameSite || c.onSeedHost(canonicalURL(canonical)) || c.onSubdomain(canonical) {
+			page.canonical = c.upgrade(c.opts.cleanURL(canonical))
+		} else {
+			currentLogger().Warn("omitting page canonicalized to another domain", "url", n.link.Href, "canonical", canonical)
+			page.omit = true
+		}
+	}
+
+	// Attach the page's own media for the image and video sitemap extensions, and
+	// mirror its declared language variants into its sitemap entry
+	page.link.Images = facts.Images
+	page.link.Videos = facts.Videos
+	page.link.Alternates = facts.Alternates
+
+	// A meta refresh is a redirect in all but name, so like an HTTP redirect it is
+	// followed even from a nofollow page, and its target is crawled at the same depth
+	if href := facts.Refresh; href != "" {
+		if c.internal(n.link.Href)(href) {
+			href = c.upgrade(c.opts.cleanURL(href))
+			if c.opts.Robots.Allowed(href) && c.opts.wanted(href) && !c.visited.contains(href) {
+				page.refresh = Link{Href: href, Parent: n.link.Href}
+			}
+		}
+	}
+
+	// A nofollow page is listed, but its links must not be discovered through it
+	if facts.Nofollow && !c.opts.IgnoreNofollow {
+		return page
+	}
+
+	// Go through all internal links of the current page, dropping those already known,
+	// those the site asks crawlers to stay away from and those the caller excluded
+	for _, neighbor := range facts.Links {
+		cleaned := c.opts.cleanURL(neighbor.Href)
+		neighbor.Href = c.upgrade(cleaned)
+		if !c.opts.Robots.Allowed(neighbor.Href) || !c.opts.wanted(neighbor.Href) {
+			continue
+		}
+		if c.opts.Graph != nil {
+			page.links = append(page.links, neighbor.Href)
+		}
+		if !c.visited.contains(neighbor.Href) {
+			if c.opts.HTTPSOnly && neighbor.Href != cleaned {
+				currentLogger().Warn("upgrading http link to https", "url", cleaned, "page", n.link.Href)
+			}
+			neighbor.Parent = n.link.Href
+			page.neighbors = append(page.neighbors, neighbor)
+		}
+	}
+
+	return page
+}
+
+// readPage reads the title, robots directives, canonical URL, media, meta refresh target
+// and internal links of a fetched page. Media are only collected when the crawl lists them.
+//
+// Parameters:
+//   - fetched: The page, fetched successfully
+//   - pageURL: URL the page was fetched from, against which its links are resolved
+//
+// Returns:
+//   - pageFacts: What the page says about itself and links to
+func (c *crawler) readPage(fetched fetchedPage, pageURL string) pageFacts {
+	doc := fetched.doc
+	facts := pageFacts{
+		Title:       ExtractTitle(doc),
+		Description: ExtractMetaDescription(doc),
+	}
+
+	metaNoindex, metaNofollow := ParseRobotsMetaTag(doc)
+	headerNoindex, headerNofollow := ParseXRobotsHeader(strings.Join(fetched.header.Values("X-Robots-Tag"), ","))
+	facts.Noindex = metaNoindex || headerNoindex
+	facts.Nofollow = metaNofollow || headerNofollow
+
+	if canonical, sameSite, ok := resolveCanonical(doc, pageURL); ok {
+		facts.Canonical, facts.CanonicalSameSite = canonical, sameSite
+	}
+
+	if c.opts.Images {
+		facts.Images = ExtractImages(doc, pageURL)
+	}
+	if c.opts.Videos {
+		facts.Videos = ExtractVideos(doc, pageURL)
+	}
+	if c.opts.Hreflang {
+		facts.Alternates = extractAlternates(doc, pageURL)
+	}
+
+	if target, ok := ExtractMetaRefresh(doc); ok {
+		facts.Refresh = ResolveURL(pageURL, target)
+	}
+	facts.Links = extractLinks(doc, pageURL, c.internal(pageURL))
+	return facts
+}
+
+// cacheSettings describes the seeds and the settings that change what readPage reads
+// from a page, so that a PageCache is only used by crawls reading pages the same way.
+func (c *crawler) cacheSettings() string {
+	return fmt.Sprintf("seeds=%s images=%t videos=%t hreflang=%t strict-scheme=%t subdomains=%t prefer-https=%t https-only=%t",
+		strings.Join(c.seeds, " "), c.opts.Images, c.opts.Videos, c.opts.Hreflang,
+		c.opts.StrictScheme, c.opts.IncludeSubdomains, c.opts.PreferHTTPS, c.opts.HTTPSOnly)
+}
+
+// resolveCanonical resolves the canonical URL declared by a page against the page URL.
+// It reports ok only when the page declares a valid canonical URL different from its own,
+// and sameSite when that URL is served from the same host.
+func resolveCanonical(doc *html.Node, pageURL string) (canonical string, sameSite, ok bool) {
+	href, ok := ExtractCanonical(doc)
+	if !ok {
+		return "", false, false
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", false, false
+	}
+	canonical, ok = resolveResource(base, href)
+	if !ok || canonical == pageURL {
+		return "", false, false
+	}
+	return canonical, sameHost(canonical, base), true
+}
+
+// waitCrawlDelay spaces the requests to the host of rawURL by the Crawl-delay of its own
+// robots.txt across all workers, capped by MaxCrawlDelay, when that host is not the one
+// CrawlOptions.Robots was created for, whose Crawl-delay is part of EffectiveDelay.
+func (c *crawler) waitCrawlDelay(ctx context.Context, rawURL string) error {
+	robots, _ := c.opts.Robots.For(rawURL)
+	if robots == nil || robots == c.opts.Robots {
+		return nil
+	}
+	delay := robots.CrawlDelay()
+	if c.opts.MaxCrawlDelay > 0 {
+		delay = min(delay, c.opts.MaxCrawlDelay)
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	host := strings.ToLower(hostOf(rawURL))
+	c.delayMu.Lock()
+	limiter, ok := c.delays[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(delay), 1)
+		c.delays[host] = limiter
+	}
+	c.delayMu.Unlock()
+	return limiter.Wait(ctx)
+}
+
+// EffectiveDelay returns the minimum interval between the fetches of each worker: Delay,
+// or the Crawl-delay of Robots capped at MaxCrawlDelay if that is larger.
+func (o CrawlOptions) EffectiveDelay() time.Duration {
+	crawlDelay := o.Robots.CrawlDelay()
+	if o.MaxCrawlDelay > 0 {
+		crawlDelay = min(crawlDelay, o.MaxCrawlDelay)
+	}
+	return max(o.Delay, crawlDelay)
+}
+
+// userAgent returns the configured User-Agent, falling back to DefaultUserAgent.
+func (o CrawlOptions) userAgent() string {
+	if o.UserAgent != "" {
+		return o.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+// internal returns the IsInternalLink test for links on pageURL, accepting both web
+// schemes unless StrictScheme is set, extended to links to the host of any seed, by
+// PreferHTTPS and HTTPSOnly to http:// links that upgrade to an https host and by
+// IncludeSubdomains to links to the subdomains of the seeds.
+func (c *crawler) internal(pageURL string) func(href string) bool {
+	return func(href string) bool {
+		if internalLink(href, pageURL, c.opts.StrictScheme) || ((c.opts.PreferHTTPS || c.opts.HTTPSOnly) && IsInternalLink(c.upgrade(canonicalURL(href)), pageURL)) {
+			return true
+		}
+
+		// Only links naming a host can lead to another seed's site or another subdomain
+		u, err := url.Parse(href)
+		if err != nil || u.Host == "" {
+			return false
+		}
+		target := c.upgrade(canonicalURL(ResolveURL(pageURL, href)))
+		return c.onSeedHost(target) || c.onSubdomain(target)
+	}
+}
+
+// onSeedHost reports whether rawURL, in canonical form, is on the scheme and host of one
+// of the seeds, or on its host with the other web scheme unless StrictScheme is set.
+func (c *crawler) onSeedHost(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if c.opts.StrictScheme || !isWebScheme(u.Scheme) {
+		return c.origins[u.Scheme+"://"+u.Host]
+	}
+	return c.origins["http://"+u.Host] || c.origins["https://"+u.Host]
+}
+
+// onSubdomain reports whether IncludeSubdomains is set and rawURL is an http or https URL
+// on the registrable domain of a seed or one of its subdomains. Hosts are compared by
+// whole labels, so evil-example.com is not a subdomain of example.com.
+func (c *crawler) onSubdomain(rawURL string) bool {
+	if !c.opts.IncludeSubdomains {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for domain := range c.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// registrableDomain returns the domain one label below the public suffix of host, such as
+// example.co.uk for www.example.co.uk, or host itself, lowercased, for IP addresses and
+// hosts without a known public suffix such as localhost.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// upgrade returns rawURL with its scheme changed to https if it is an http:// URL and
+// either HTTPSOnly is set, or PreferHTTPS is set and rawURL is on the host of an https
+// seed. rawURL must be in canonical form.
+func (c *crawler) upgrade(rawURL string) string {
+	if !c.opts.PreferHTTPS && !c.opts.HTTPSOnly {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" || !(c.opts.HTTPSOnly || c.secure[u.Host]) {
+		return rawURL
+	}
+	u.Scheme = "https"
+	return u.String()
+}
+
+// downgrade returns the http:// form of an https:// URL on the host of an https seed,
+// to retry a page PreferHTTPS upgraded when its https form cannot be reached. HTTPSOnly
+// rules out the fallback.
+func (c *crawler) downgrade(rawURL string) (string, bool) {
+	if !c.opts.PreferHTTPS || c.opts.HTTPSOnly {
+		return "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || !c.secure[u.Host] {
+		return "", false
+	}
+	u.Scheme = "http"
+	return u.String(), true
+}
+
+// cleanURL is the single place where a URL entering the crawl is reduced to the form that
+// is checked against the visited URLs, queued and listed: without fragment, with the
+// scheme and host lowercased, default ports and dot-segments removed, with
+// StripQuery without query parameters other than KeepQueryParams, and with the
+// TrailingSlash policy applied.
+func (o CrawlOptions) cleanURL(rawURL string) string {
+	rawURL = canonicalURL(withoutFragment(rawURL))
+	if o.StripQuery {
+		rawURL = StripQuery(rawURL, o.KeepQueryParams)
+	}
+	return ApplyTrailingSlash(rawURL, o.TrailingSlash)
+}
+
+// wanted reports whether rawURL passes the configured URL filters: it must not match any
+// exclude pattern, its path must match one of the include patterns if any are configured,
+// and the LinkFilter must accept it.
+func (o CrawlOptions) wanted(rawURL string) bool {
+	matches := func(s string) func(*regexp.Regexp) bool {
+		return func(re *regexp.Regexp) bool { return re.MatchString(s) }
+	}
+
+	if slices.ContainsFunc(o.ExcludePatterns, matches(rawURL)) {
+		return false
+	}
+	if len(o.IncludePatterns) > 0 {
+		u, err := url.Parse(rawURL)
+		if err != nil || !slices.ContainsFunc(o.IncludePatterns, matches(u.Path)) {
+			return false
+		}
+	}
+	return o.LinkFilter == nil || o.LinkFilter(rawURL)
+}
+
+// lastModified converts the Last-Modified response header into the W3C datetime format
+// required by the sitemap protocol. It returns an empty string when the header is missing
+// or cannot be parsed, so that the <lastmod> element is omitted.
+func lastModified(header http.Header) string {
+	t, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
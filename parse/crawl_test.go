@@ -0,0 +1,250 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCrawlHonorsRobotsOfEverySeed(t *testing.T) {
+	first := testSite{
+		"/robots.txt": "User-agent: *\nDisallow: /admin\n",
+		"/":           `<a href="/admin">Admin</a> <a href="/private">Private</a>`,
+		"/admin":      `<p>Admin</p>`,
+		"/private":    `<p>Private</p>`,
+	}.serve(t)
+	second := testSite{
+		"/robots.txt": "User-agent: *\nDisallow: /private\n",
+		"/":           `<a href="/admin">Admin</a> <a href="/private">Private</a>`,
+		"/admin":      `<p>Admin</p>`,
+		"/private":    `<p>Private</p>`,
+	}.serve(t)
+
+	robots, err := NewRobotsFilter(context.Background(), first.URL, http.DefaultClient, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("NewRobotsFilter: %v", err)
+	}
+	result, err := CrawlBFS(context.Background(), []Link{{Href: first.URL + "/"}, {Href: second.URL + "/"}},
+		WithRobotsFilter(robots))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+
+	got := crawlHrefs(result)
+	slices.Sort(got)
+	want := []string{first.URL + "/", first.URL + "/private", second.URL + "/", second.URL + "/admin"}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+}
+
+func TestCrawlBFSFinishesLevelOnCancel(t *testing.T) {
+	site := testSite{
+		"/":  `<a href="/a">A</a> <a href="/b">B</a>`,
+		"/a": `<a href="/c">C</a>`,
+		"/b": `<p>B</p>`,
+		"/c": `<p>C</p>`,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Interrupt while the first page of depth 1 is being fetched
+		if r.URL.Path == "/a" {
+			cancel()
+		}
+		site.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	result, err := CrawlBFS(ctx, []Link{{Href: srv.URL + "/"}}, WithConcurrency(1))
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("CrawlBFS error = %v, want an interruption", err)
+	}
+	if !result.Interrupted || !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Interrupted = %v, Err = %v, want the cancellation recorded", result.Interrupted, result.Err)
+	}
+	if result.PagesVisited != 3 || result.PagesFailed != 0 {
+		t.Errorf("fetched %d pages, %d failed; want the whole of depth 1 fetched", result.PagesVisited, result.PagesFailed)
+	}
+	for _, href := range crawlHrefs(result) {
+		if href == srv.URL+"/c" {
+			t.Errorf("depth 2 was crawled after the interruption")
+		}
+	}
+}
+
+func TestCrawlBFSStopsAtDeadline(t *testing.T) {
+	site := testSite{
+		"/":     `<a href="/slow">Slow</a> <a href="/fast">Fast</a>`,
+		"/fast": `<p>Fast</p>`,
+		"/slow": `<p>Slow</p>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The slow page only answers long after the deadline, unless the request is aborted
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(10 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		site.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := CrawlBFS(ctx, []Link{{Href: srv.URL + "/"}}, WithConcurrency(2), WithMaxDepth(5))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CrawlBFS returned after %s, want the slow request aborted at the deadline", elapsed)
+	}
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CrawlBFS error = %v, want the deadline exceeded", err)
+	}
+	if !result.Interrupted || !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("Interrupted = %v, Err = %v, want the deadline recorded", result.Interrupted, result.Err)
+	}
+}
+
+func TestCrawlBFSConcurrencyKeepsURLSet(t *testing.T) {
+	srv := testSite{
+		"/":             `<a href="/docs">Docs</a> <a href="/blog">Blog</a> <a href="/about">About</a>`,
+		"/docs":         `<a href="/docs/install">Install</a> <a href="/docs/usage">Usage</a> <a href="/">Home</a>`,
+		"/docs/install": `<a href="/docs/usage">Usage</a> <a href="/faq">FAQ</a>`,
+		"/docs/usage":   `<a href="/docs/install">Install</a> <a href="/docs/api">API</a>`,
+		"/docs/api":     `<p>API</p>`,
+		"/blog":         `<a href="/blog/one">One</a> <a href="/blog/two">Two</a> <a href="/missing">Old post</a>`,
+		"/blog/one":     `<a href="/blog/two">Two</a> <a href="/about">About</a>`,
+		"/blog/two":     `<a href="/blog/one">One</a>`,
+		"/about":        `<a href="/faq">FAQ</a>`,
+		"/faq":          `<p>FAQ</p>`,
+	}.serve(t)
+
+	var want []string
+	for _, concurrency := range []int{1, 8} {
+		result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}}, WithConcurrency(concurrency))
+		if err != nil {
+			t.Fatalf("CrawlBFS with concurrency %d: %v", concurrency, err)
+		}
+		got := crawlHrefs(result)
+		slices.Sort(got)
+		if want == nil {
+			want = got
+			continue
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("concurrency %d listed %v, concurrency 1 listed %v", concurrency, got, want)
+		}
+	}
+	if len(want) != 10 {
+		t.Errorf("listed %d URLs, want the 10 pages of the site: %v", len(want), want)
+	}
+}
+
+func TestCrawlTableOfContentsListsOnePage(t *testing.T) {
+	srv := testSite{"/docs/install": tocPage}.serve(t)
+
+	result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/docs/install#linux"}})
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+	want := []string{srv.URL + "/docs/install"}
+	if got := crawlHrefs(result); !slices.Equal(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+	if result.PagesVisited != 1 {
+		t.Errorf("fetched %d pages, want the page fetched once", result.PagesVisited)
+	}
+}
+
+func TestIncludeExcludePatterns(t *testing.T) {
+	re := regexp.MustCompile
+	tests := []struct {
+		name    string
+		include []*regexp.Regexp
+		exclude []*regexp.Regexp
+		url     string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "https://example.com/blog", true},
+		{"include matches", []*regexp.Regexp{re(`^/docs/`)}, nil, "https://example.com/docs/install", true},
+		{"include misses", []*regexp.Regexp{re(`^/docs/`)}, nil, "https://example.com/blog/post", false},
+		{"include matches the path only", []*regexp.Regexp{re(`^/docs/`)}, nil, "https://example.com/blog?next=/docs/", false},
+		{"any include suffices", []*regexp.Regexp{re(`^/docs/`), re(`^/api/`)}, nil, "https://example.com/api/v1", true},
+		{"exclude matches", nil, []*regexp.Regexp{re(`/private/`)}, "https://example.com/private/x", false},
+		{"exclude matches the whole URL", nil, []*regexp.Regexp{re(`\?session=`)}, "https://example.com/docs?session=1", false},
+		{"exclude wins over include", []*regexp.Regexp{re(`^/docs/`)}, []*regexp.Regexp{re(`/docs/internal/`)}, "https://example.com/docs/internal/notes", false},
+		{"overlap leaves the rest included", []*regexp.Regexp{re(`^/docs/`)}, []*regexp.Regexp{re(`/docs/internal/`)}, "https://example.com/docs/public", true},
+		{"identical patterns exclude", []*regexp.Regexp{re(`^/docs/`)}, []*regexp.Regexp{re(`/docs/`)}, "https://example.com/docs/install", false},
+		{"exclude outside the included tree", []*regexp.Regexp{re(`^/docs/`)}, []*regexp.Regexp{re(`^https://example\.com/blog`)}, "https://example.com/docs/blog", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := CrawlOptions{IncludePatterns: tt.include, ExcludePatterns: tt.exclude}
+			if got := opts.wanted(tt.url); got != tt.want {
+				t.Errorf("wanted(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlStoresFragmentFreeURLs(t *testing.T) {
+	srv := testSite{
+		"/":     `<a href="#section">Section</a> <a href="/docs#section1">Docs 1</a> <a href="/docs#section2">Docs 2</a>`,
+		"/docs": `<a href="/#section">Home</a> <a href="#section1">Section 1</a>`,
+	}.serve(t)
+
+	result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}})
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+	want := []string{srv.URL + "/", srv.URL + "/docs"}
+	if got := crawlHrefs(result); !slices.Equal(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+	if result.PagesVisited != 2 {
+		t.Errorf("fetched %d pages, want 2", result.PagesVisited)
+	}
+}
+
+func TestCrawlFollowsBaseHref(t *testing.T) {
+	srv := testSite{
+		"/":                       `<a href="/docs/intro">Intro</a>`,
+		"/docs/intro":             `<head><base href="/archive/2020/"></head><a href="page.html">Page</a>`,
+		"/archive/2020/page.html": `<p>Page</p>`,
+		"/docs/page.html":         `<p>Wrong page</p>`,
+	}.serve(t)
+
+	result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}})
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+	want := []string{srv.URL + "/", srv.URL + "/docs/intro", srv.URL + "/archive/2020/page.html"}
+	if got := crawlHrefs(result); !slices.Equal(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+}
+
+func BenchmarkCrawlBFS(b *testing.B) {
+	srv := treeOfPages(255).serve(b)
+	seeds := []Link{{Href: srv.URL + "/"}}
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				result, err := CrawlBFS(context.Background(), seeds, WithMaxDepth(10), WithConcurrency(concurrency))
+				if err != nil {
+					b.Fatalf("CrawlBFS: %v", err)
+				}
+				b.ReportMetric(float64(result.PagesVisited), "pages/op")
+			}
+		})
+	}
+}
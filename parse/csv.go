@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{"url", "depth", "status", "content_type", "linked_from"}
+
+// WriteCSV writes links as a CSV table with a header row followed by one row per URL.
+// Rows are sorted by URL (and then by depth) so that the output of two crawls of the
+// same site can be diffed meaningfully. Quoting of URLs containing commas or quotes is
+// handled by encoding/csv.
+//
+// Parameters:
+//   - links: Crawled links including their depth, status and parent metadata
+//   - w: Destination for the CSV document
+//
+// Returns:
+//   - error: Any error that occurred while writing
+func WriteCSV(links []Link, w io.Writer) error {
+	// Sort a copy so the caller's slice keeps its crawl order
+	sorted := slices.Clone(links)
+	slices.SortStableFunc(sorted, compareLinks)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, link := range sorted {
+		record := []string{
+			link.Href,
+			strconv.Itoa(link.Depth),
+			strconv.Itoa(link.StatusCode),
+			link.ContentType,
+			link.Parent,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing CSV row for %s: %w", link.Href, err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
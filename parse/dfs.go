@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// CrawlDFS performs a depth-first search crawl of a website starting from the provided links.
+// It follows the first link of every page as deep as WithMaxDepth allows before backtracking,
+// which reaches the bottom of large taxonomies sooner than CrawlBFS. Visited URLs are
+// tracked exactly as in CrawlBFS, so cyclic link structures terminate.
+//
+// The crawl is iterative, using an explicit stack, and sequential: pages are fetched one
+// at a time and WithConcurrency is ignored. A page is expanded at the depth it was first
+// discovered at, so with a depth limit DFS may list fewer pages than CrawlBFS when a page
+// is first reached through a long path; without a binding limit both list the same set.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the crawl
+//   - links: Seed links to start crawling from, all at depth 0
+//   - options: Crawl settings as for CrawlBFS; WithConcurrency is ignored
+//
+// Returns:
+//   - *CrawlResult: All unique internal links discovered, in discovery order, with
+//     statistics; partial if the crawl was cancelled
+//   - error: Any error that prevented the crawl from starting or completing
+func CrawlDFS(ctx context.Context, links []Link, options ...CrawlOption) (_ *CrawlResult, err error) {
+	start := time.Now()
+	cfg := newCrawlConfig(options)
+	c, seeds, err := newCrawler(links, cfg.client, cfg.opts)
+	if err != nil {
+		return nil, err
+	}
+	maxDepth := cfg.maxDepth
+	finish := c.startStream(cfg.stream)
+	defer func() { err = errors.Join(err, finish()) }()
+
+	// Store all discovered links for the final sitemap, along with crawl statistics
+	result := &CrawlResult{}
+
+	// Push in reverse so the first seed, and later the first link of a page, is popped first
+	stack := slices.Clone(seeds)
+	slices.Reverse(stack)
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			result.Duration, result.Interrupted, result.Err = time.Since(start), true, err
+			return result, fmt.Errorf("%w: %w", ErrInterrupted, err)
+		}
+
+		n := stack[len(stack)-1]
+		expand := n.depth < maxDepth
+		if expand || c.opts.Verify {
+			// Out of budget: whatever is known but unfetched can still be listed on request
+			if c.remainingPages(result) == 0 {
+				slices.Reverse(stack)
+				c.truncate(result, stack)
+				break
+			}
+			c.progress.enqueue(1)
+		}
+		stack = stack[:len(stack)-1]
+		page := c.crawlPage(ctx, n, expand, &c.pacers[0])
+
+		fresh := c.merge(page, result)
+		for i := len(fresh) - 1; i >= 0; i-- {
+			stack = append(stack, node{fresh[i], n.depth + 1})
+		}
+
+		// A meta refresh target is crawled next, at the depth of the page redirecting to it
+		if target, ok := c.refreshTarget(page); ok {
+			stack = append(stack, node{target, n.depth})
+		}
+	}
+
+	// The last page may have been cut short by cancellation too
+	result.Duration = time.Since(start)
+	if err := ctx.Err(); err != nil {
+		result.Interrupted, result.Err = true, err
+		return result, fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// treeSite is a site whose /a branch is three levels deep and /b branch two.
+var treeSite = testSite{
+	"/":      `<a href="/a">A</a> <a href="/b">B</a>`,
+	"/a":     `<a href="/a/1">A1</a> <a href="/">Home</a>`,
+	"/a/1":   `<a href="/a/1/x">A1x</a> <a href="/a">A</a>`,
+	"/a/1/x": `<a href="/b">B</a>`,
+	"/b":     `<a href="/b/1">B1</a>`,
+	"/b/1":   `<a href="/">Home</a>`,
+}
+
+// pathsOf returns the URLs of links relative to the root of srvURL.
+func pathsOf(srvURL string, hrefs []string) []string {
+	paths := make([]string, len(hrefs))
+	for i, href := range hrefs {
+		paths[i] = strings.TrimPrefix(href, srvURL)
+	}
+	return paths
+}
+
+func TestDiscoveryOrderDiffersBetweenStrategies(t *testing.T) {
+	srv := treeSite.serve(t)
+	seeds := []Link{{Href: srv.URL + "/"}}
+
+	bfs, err := CrawlBFS(context.Background(), seeds)
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+	dfs, err := CrawlDFS(context.Background(), seeds)
+	if err != nil {
+		t.Fatalf("CrawlDFS: %v", err)
+	}
+
+	wantBFS := []string{"/", "/a", "/b", "/a/1", "/b/1", "/a/1/x"}
+	wantDFS := []string{"/", "/a", "/a/1", "/a/1/x", "/b", "/b/1"}
+	if got := pathsOf(srv.URL, crawlHrefs(bfs)); !slices.Equal(got, wantBFS) {
+		t.Errorf("BFS discovered %v, want %v", got, wantBFS)
+	}
+	if got := pathsOf(srv.URL, crawlHrefs(dfs)); !slices.Equal(got, wantDFS) {
+		t.Errorf("DFS discovered %v, want %v", got, wantDFS)
+	}
+}
+
+func TestStrategiesListSameSet(t *testing.T) {
+	srv := testSite{
+		"/":                     `<a href="/archive">Archive</a> <a href="/blog">Blog</a> <a href="/about">About</a>`,
+		"/archive":              `<a href="/archive/2019">2019</a> <a href="/archive/2020">2020</a> <a href="/">Home</a>`,
+		"/archive/2019":         `<a href="/archive/2019/01">January</a> <a href="/archive">Archive</a>`,
+		"/archive/2019/01":      `<a href="/archive/2019/01/post">Post</a> <a href="/report.pdf">Report</a>`,
+		"/archive/2019/01/post": `<a href="/blog/latest">Latest</a> <a href="/gone">Gone</a>`,
+		"/archive/2020":         `<a href="/archive/2019">2019</a>`,
+		"/blog":                 `<a href="/blog/latest">Latest</a> <a href="/archive/2020">2020</a>`,
+		"/blog/latest":          `<a href="/blog">Blog</a> <a href="/about">About</a>`,
+		"/about":                `<a href="/">Home</a>`,
+		"/report.pdf":           "%PDF-1.4",
+	}.serve(t)
+	seeds := []Link{{Href: srv.URL + "/"}}
+
+	bfs, err := CrawlBFS(context.Background(), seeds, WithMaxDepth(10), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+	dfs, err := CrawlDFS(context.Background(), seeds, WithMaxDepth(10))
+	if err != nil {
+		t.Fatalf("CrawlDFS: %v", err)
+	}
+
+	bfsSet, dfsSet := crawlHrefs(bfs), crawlHrefs(dfs)
+	if slices.Equal(bfsSet, dfsSet) {
+		t.Error("BFS and DFS listed the pages in the same order; the fixture does not tell them apart")
+	}
+	slices.Sort(bfsSet)
+	slices.Sort(dfsSet)
+	if !slices.Equal(bfsSet, dfsSet) {
+		t.Errorf("BFS listed %v, DFS listed %v", bfsSet, dfsSet)
+	}
+	if len(bfsSet) != 10 {
+		t.Errorf("listed %d URLs, want the 10 pages of the site: %v", len(bfsSet), bfsSet)
+	}
+	if bfs.PagesVisited != dfs.PagesVisited || bfs.PagesFailed != dfs.PagesFailed {
+		t.Errorf("BFS fetched %d pages with %d failures, DFS %d with %d",
+			bfs.PagesVisited, bfs.PagesFailed, dfs.PagesVisited, dfs.PagesFailed)
+	}
+}
+
+func BenchmarkCrawlDFS(b *testing.B) {
+	srv := treeOfPages(255).serve(b)
+	seeds := []Link{{Href: srv.URL + "/"}}
+	for b.Loop() {
+		result, err := CrawlDFS(context.Background(), seeds, WithMaxDepth(10))
+		if err != nil {
+			b.Fatalf("CrawlDFS: %v", err)
+		}
+		b.ReportMetric(float64(result.PagesVisited), "pages/op")
+	}
+}
@@ -0,0 +1,234 @@
+package parse
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// NormalizeURL reduces a URL to a canonical form for comparison, so that equivalent
+// spellings of a page are crawled once and cosmetic differences between two sitemaps are
+// not reported as changes. The scheme and host are lowercased, default ports and
+// fragments are dropped, dot-segments such as /a/../b are resolved, a trailing slash is
+// removed from every path except the root, an empty query string is removed and query
+// parameters are sorted by name. The case of the path is kept, since paths are
+// case-sensitive.
+//
+// Parameters:
+//   - rawURL: The URL to normalize
+//
+// Returns:
+//   - string: The normalized URL
+//   - error: rawURL cannot be parsed
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("normalizing URL %s: %w", rawURL, err)
+	}
+
+	canonicalize(u)
+	u.Fragment, u.RawFragment = "", ""
+
+	// "https://example.com" and "https://example.com/" are the same page
+	if u.Path == "" {
+		u.Path = "/"
+	} else if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+
+	// "?b=2&a=1", "?a=1&b=2" and, without parameters, "?" and "" are the same query;
+	// a query that does not parse as key=value pairs is kept as it is
+	u.ForceQuery = false
+	if query, err := url.ParseQuery(u.RawQuery); err == nil {
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// canonicalize rewrites u in place to the spelling under which the crawler stores it:
+// lowercase scheme and host, no default port and no dot-segments in the path.
+func canonicalize(u *url.URL) {
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port) // Keeps the brackets of an IPv6 address
+	}
+	if strings.Contains(u.Path, "/.") {
+		*u = *u.ResolveReference(&url.URL{}) // Resolving an empty reference removes dot-segments
+	}
+}
+
+// canonicalURL returns rawURL rewritten by canonicalize, or rawURL itself if it cannot be
+// parsed.
+func canonicalURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	canonicalize(u)
+	return u.String()
+}
+
+// StripQuery removes the query string from rawURL, except for the parameters named in
+// keep, so that the variants of a page produced by faceted navigation or tracking
+// parameters collapse into one URL. Kept parameters retain their order and encoding.
+//
+// Parameters:
+//   - rawURL: The URL to strip
+//   - keep: Names of query parameters that select different content, such as "page"
+//
+// Returns:
+//   - string: rawURL without the other parameters, or rawURL itself if it cannot be parsed
+func StripQuery(rawURL string, keep []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(name); err == nil && slices.Contains(keep, name) {
+			kept = append(kept, pair)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// Trailing slash policies, as accepted by ApplyTrailingSlash.
+const (
+	TrailingSlashKeep  = "keep"  // List every URL as it was discovered
+	TrailingSlashAdd   = "add"   // End every directory-like path with a slash
+	TrailingSlashStrip = "strip" // Remove the trailing slash from every path but the root
+)
+
+// TrailingSlashPolicies lists the policies accepted by ApplyTrailingSlash.
+var TrailingSlashPolicies = []string{TrailingSlashKeep, TrailingSlashAdd, TrailingSlashStrip}
+
+// ApplyTrailingSlash rewrites the path of rawURL so that /about and /about/ are always
+// spelled the same way. TrailingSlashAdd leaves paths whose last segment looks like a
+// file, such as /feed.xml, unchanged, and TrailingSlashStrip never strips the root path
+// "/". Unknown policies behave like TrailingSlashKeep.
+//
+// Parameters:
+//   - rawURL: The URL to rewrite
+//   - policy: TrailingSlashKeep, TrailingSlashAdd or TrailingSlashStrip
+//
+// Returns:
+//   - string: The rewritten URL, or rawURL itself if it cannot be parsed
+func ApplyTrailingSlash(rawURL, policy string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	switch policy {
+	case TrailingSlashAdd:
+		lastSegment := u.Path[strings.LastIndex(u.Path, "/")+1:]
+		if strings.Contains(lastSegment, ".") {
+			return rawURL
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+			if u.RawPath != "" {
+				u.RawPath += "/"
+			}
+		}
+	case TrailingSlashStrip:
+		if len(u.Path) > 1 {
+			u.Path = strings.TrimSuffix(u.Path, "/")
+			u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+		}
+	default:
+		return rawURL
+	}
+	return u.String()
+}
+
+// normalizedKey returns the normalized form of rawURL, or rawURL itself if it cannot be
+// parsed, for use as a set key.
+func normalizedKey(rawURL string) string {
+	if normalized, err := NormalizeURL(rawURL); err == nil {
+		return normalized
+	}
+	return rawURL
+}
+
+// DiffURLs compares two sets of URLs after normalizing them with NormalizeURL.
+//
+// Parameters:
+//   - old: URLs of the previous sitemap
+//   - new: URLs of the fresh crawl
+//
+// Returns:
+//   - added: Normalized URLs only present in new, sorted
+//   - removed: Normalized URLs only present in old, sorted
+func DiffURLs(old, new []string) (added, removed []string) {
+	oldSet := normalizedSet(old)
+	newSet := normalizedSet(new)
+
+	for u := range newSet {
+		if _, exists := oldSet[u]; !exists {
+			added = append(added, u)
+		}
+	}
+	for u := range oldSet {
+		if _, exists := newSet[u]; !exists {
+			removed = append(removed, u)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// DiffSitemaps compares the entries of two sitemaps by their location, normalized with
+// NormalizeURL, and returns the entries themselves rather than their URLs, so that their
+// metadata remains available. A location listed several times is reported once.
+//
+// Parameters:
+//   - old: Entries of the previous sitemap
+//   - new: Entries of the fresh crawl
+//
+// Returns:
+//   - added: Entries of new whose location is not in old, sorted by normalized location
+//   - removed: Entries of old whose location is not in new, sorted by normalized location
+func DiffSitemaps(old, new []Url) (added, removed []Url) {
+	return missingURLs(new, old), missingURLs(old, new)
+}
+
+// missingURLs returns the entries of urls whose normalized location is not among those of
+// others, the first entry of every location only, sorted by normalized location.
+func missingURLs(urls, others []Url) []Url {
+	known := make(map[string]struct{}, len(others))
+	for _, u := range others {
+		known[normalizedKey(u.Loc)] = struct{}{}
+	}
+
+	var missing []Url
+	for _, u := range urls {
+		key := normalizedKey(u.Loc)
+		if _, exists := known[key]; !exists {
+			known[key] = struct{}{} // Later entries for the same location are duplicates
+			missing = append(missing, u)
+		}
+	}
+	slices.SortFunc(missing, func(a, b Url) int {
+		return strings.Compare(normalizedKey(a.Loc), normalizedKey(b.Loc))
+	})
+	return missing
+}
+
+// normalizedSet returns the set of normalized forms of urls.
+func normalizedSet(urls []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		set[normalizedKey(u)] = struct{}{}
+	}
+	return set
+}
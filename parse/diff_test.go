@@ -0,0 +1,45 @@
+package parse
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"already normalized", "https://example.com/about", "https://example.com/about"},
+		{"already normalized root", "https://example.com/", "https://example.com/"},
+		{"already normalized query", "https://example.com/search?a=1&b=2", "https://example.com/search?a=1&b=2"},
+		{"mixed-case scheme and host", "HTTP://Example.COM/About", "http://example.com/About"},
+		{"mixed-case subdomain", "https://WWW.Example.com/Docs/Install", "https://www.example.com/Docs/Install"},
+		{"default http port", "http://example.com:80/about", "http://example.com/about"},
+		{"default https port", "https://example.com:443/about", "https://example.com/about"},
+		{"all at once", "HTTP://Example.COM:80/About", "http://example.com/About"},
+		{"other port kept", "https://example.com:8443/about", "https://example.com:8443/about"},
+		{"port default for the other scheme kept", "https://example.com:80/about", "https://example.com:80/about"},
+		{"IPv6 host with default port", "http://[::1]:80/about", "http://[::1]/about"},
+		{"dot-segments", "https://example.com/docs/../blog/./post", "https://example.com/blog/post"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"trailing slash", "https://example.com/docs/", "https://example.com/docs"},
+		{"fragment", "https://example.com/docs#install", "https://example.com/docs"},
+		{"query order and empty query", "https://example.com/search?b=2&a=1", "https://example.com/search?a=1&b=2"},
+		{"empty query", "https://example.com/search?", "https://example.com/search"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			if err != nil {
+				t.Fatalf("NormalizeURL(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+			if again, _ := NormalizeURL(got); again != got {
+				t.Errorf("NormalizeURL(%q) = %q, not stable", got, again)
+			}
+		})
+	}
+
+	if _, err := NormalizeURL("http://[::1"); err == nil {
+		t.Error("NormalizeURL accepted a malformed URL")
+	}
+}
@@ -0,0 +1,136 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// depthColors are the node fill colors used by WriteDOT, indexed by crawl depth.
+// Deeper pages use the last color.
+var depthColors = []string{"#08519c", "#3182bd", "#6baed6", "#9ecae1", "#c6dbef", "#eff3ff"}
+
+// Edge is a link from one crawled page to another page of the same site.
+type Edge struct {
+	From string // URL of the page containing the link
+	To   string // URL the link points to
+}
+
+// Graph records the link structure of a crawl. Pass a Graph in CrawlOptions.Graph to
+// have CrawlBFS fill it in, then render it with WriteDOT. A Graph is not safe for
+// concurrent use; CrawlBFS only updates it from its coordinating goroutine.
+type Graph struct {
+	edges  []Edge
+	seen   map[Edge]struct{}
+	nodes  []string
+	depths map[string]int
+}
+
+// NewGraph creates an empty crawl graph.
+func NewGraph() *Graph {
+	return &Graph{
+		seen:   make(map[Edge]struct{}),
+		depths: make(map[string]int),
+	}
+}
+
+// AddEdge records a link between two pages. Duplicate edges and links from a page to
+// itself are ignored.
+func (g *Graph) AddEdge(from, to string) {
+	e := Edge{From: from, To: to}
+	if _, exists := g.seen[e]; exists || from == to {
+		return
+	}
+	g.seen[e] = struct{}{}
+	g.edges = append(g.edges, e)
+}
+
+// AddNode records a crawled page at the given depth, keeping the shallowest depth if the
+// page is added more than once.
+func (g *Graph) AddNode(rawURL string, depth int) {
+	if d, exists := g.depths[rawURL]; exists {
+		g.depths[rawURL] = min(d, depth)
+		return
+	}
+	g.depths[rawURL] = depth
+	g.nodes = append(g.nodes, rawURL)
+}
+
+// Edges returns the recorded edges in discovery order.
+func (g *Graph) Edges() []Edge {
+	return g.edges
+}
+
+// WriteDOT renders the graph as a Graphviz DOT digraph. Nodes are identified by their
+// URL, labeled with its path and filled with a color that darkens towards the start of
+// the crawl. Pages that were linked to but not crawled are drawn white.
+//
+// Parameters:
+//   - w: Destination for the DOT document
+//
+// Returns:
+//   - error: Any error that occurred while writing
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph sitemap {")
+	fmt.Fprintln(bw, "  node [shape=box, style=filled, fontname=\"Helvetica\"];")
+
+	// Declare crawled pages first, then pages only known as link targets
+	declared := make(map[string]struct{})
+	declare := func(rawURL, color string) {
+		if _, exists := declared[rawURL]; exists {
+			return
+		}
+		declared[rawURL] = struct{}{}
+		fontColor := "black"
+		if color == depthColors[0] || color == depthColors[1] {
+			fontColor = "white"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, fillcolor=%s, fontcolor=%s];\n",
+			dotQuote(rawURL), dotQuote(nodeLabel(rawURL)), dotQuote(color), fontColor)
+	}
+	for _, node := range g.nodes {
+		declare(node, depthColors[min(g.depths[node], len(depthColors)-1)])
+	}
+	for _, e := range g.edges {
+		declare(e.From, "white")
+		declare(e.To, "white")
+	}
+
+	for _, e := range g.edges {
+		fmt.Fprintf(bw, "  %s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+	}
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing DOT graph: %w", err)
+	}
+	return nil
+}
+
+// nodeLabel returns the part of a URL shown on its node: the path with any query,
+// or the full URL if it cannot be parsed.
+func nodeLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	label := u.EscapedPath()
+	if label == "" {
+		label = "/"
+	}
+	if u.RawQuery != "" {
+		label += "?" + u.RawQuery
+	}
+	return label
+}
+
+// dotQuote returns s as a double-quoted DOT string, escaping backslashes, quotes and
+// line breaks so arbitrary URLs cannot break the DOT syntax.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
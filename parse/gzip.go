@@ -0,0 +1,48 @@
+package parse
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// WriteXMLGzip encodes links as an XML sitemap and writes it to w as a gzip stream,
+// suitable for serving as sitemap.xml.gz. The XML is compressed as it is produced,
+// and the decompressed content is identical to the output of WriteXML.
+//
+// Parameters:
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//   - w: Destination for the compressed sitemap
+//
+// Returns:
+//   - error: Any error that occurred during encoding, compression or writing
+func WriteXMLGzip(links []Link, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if err := WriteXML(links, gz); err != nil {
+		gz.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("finishing gzip stream: %w", err)
+	}
+	return nil
+}
+
+// ReadXMLGzip decompresses and parses a gzip-compressed XML sitemap, such as a
+// sitemap.xml.gz downloaded from a live server, and returns its URL entries.
+//
+// Parameters:
+//   - r: Source of the compressed sitemap
+//
+// Returns:
+//   - []Url: The URL entries of the sitemap in document order
+//   - error: Any error that occurred during decompression or XML parsing
+func ReadXMLGzip(r io.Reader) ([]Url, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	return ReadXML(gz)
+}
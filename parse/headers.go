@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// forbiddenHeaders lists request headers that must not be overridden through
+// CrawlOptions.Headers because they describe the connection or message framing.
+var forbiddenHeaders = []string{"Host", "Content-Length"}
+
+// ParseHeaders parses a comma-separated list of Key:Value pairs, such as
+// "Accept-Language:de,X-API-Key:secret". Because header values may themselves contain
+// commas, a comma-separated segment without a colon is treated as a continuation of the
+// previous value, so "Accept:text/html,application/xhtml+xml" yields a single header.
+//
+// Parameters:
+//   - s: The header list; empty yields no headers
+//
+// Returns:
+//   - map[string]string: Header values keyed by canonical header name
+//   - error: A pair with an empty name, or a forbidden header
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	last := ""
+	for _, segment := range strings.Split(s, ",") {
+		if strings.TrimSpace(segment) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(segment, ":")
+		if !ok {
+			if last == "" {
+				return nil, fmt.Errorf("invalid header %q (expected Key:Value)", segment)
+			}
+			headers[last] += "," + segment
+			continue
+		}
+
+		key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+		if key == "" {
+			return nil, fmt.Errorf("invalid header %q (empty name)", segment)
+		}
+		headers[key] = strings.TrimSpace(value)
+		last = key
+	}
+
+	if err := ValidateHeaders(headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// ValidateHeaders reports an error if headers contain a header that must not be set by
+// callers, such as Host or Content-Length. Header names are compared case-insensitively.
+func ValidateHeaders(headers map[string]string) error {
+	for key := range headers {
+		for _, forbidden := range forbiddenHeaders {
+			if strings.EqualFold(key, forbidden) {
+				return fmt.Errorf("header %s cannot be overridden", forbidden)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// ChangeFreqs lists the <changefreq> values allowed by the sitemap protocol.
+var ChangeFreqs = []string{"always", "hourly", "daily", "weekly", "monthly", "yearly", "never"}
+
+// ValidateChangeFreq reports an error unless value is one of the enumerated
+// <changefreq> values of the sitemap protocol.
+func ValidateChangeFreq(value string) error {
+	if !slices.Contains(ChangeFreqs, value) {
+		return fmt.Errorf("invalid changefreq %q (expected one of %v)", value, ChangeFreqs)
+	}
+	return nil
+}
+
+// ValidatePriority reports an error unless value lies within the 0.0–1.0 range
+// the sitemap protocol allows for <priority>; NaN lies in no range.
+func ValidatePriority(value float64) error {
+	if math.IsNaN(value) || value < 0 || value > 1 {
+		return fmt.Errorf("invalid priority %v (expected a value between 0.0 and 1.0)", value)
+	}
+	return nil
+}
+
+// DepthPriority derives a <priority> from a page's crawl depth: 1.0 for the start page,
+// decreasing by 0.2 per level down to a floor of 0.1. The result is rounded to one decimal
+// place, as is customary in sitemaps.
+//
+// Parameters:
+//   - depth: BFS depth at which the page was discovered
+//
+// Returns:
+//   - float64: Priority between 0.1 and 1.0
+func DepthPriority(depth int) float64 {
+	priority := math.Max(1.0-0.2*float64(depth), 0.1)
+	return math.Round(priority*10) / 10
+}
@@ -0,0 +1,53 @@
+package parse
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidatePriority(t *testing.T) {
+	tests := []struct {
+		value float64
+		ok    bool
+	}{
+		{0, true},
+		{0.5, true},
+		{1, true},
+		{-0.1, false},
+		{1.1, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	}
+	for _, tt := range tests {
+		if err := ValidatePriority(tt.value); (err == nil) != tt.ok {
+			t.Errorf("ValidatePriority(%v) = %v, want ok %v", tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestEncodeXMLKeepsZeroPriority(t *testing.T) {
+	zero, half := 0.0, 0.5
+	out, err := EncodeXML([]Link{
+		{Href: "https://example.com/", Priority: &half},
+		{Href: "https://example.com/archive", Priority: &zero},
+		{Href: "https://example.com/about"},
+	})
+	if err != nil {
+		t.Fatalf("EncodeXML: %v", err)
+	}
+	if got := strings.Count(out, "<priority>"); got != 2 {
+		t.Errorf("%d <priority> elements, want 2 in:\n%s", got, out)
+	}
+	if !strings.Contains(out, "<priority>0</priority>") {
+		t.Errorf("priority 0.0 was dropped:\n%s", out)
+	}
+
+	urls, err := ReadXML(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("ReadXML: %v", err)
+	}
+	if len(urls) != 3 || urls[1].Priority == nil || *urls[1].Priority != 0 || urls[2].Priority != nil {
+		t.Errorf("read back %+v, want priority 0 on the second entry and none on the third", urls)
+	}
+}
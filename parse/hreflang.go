@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// xhtmlNamespace is the XML namespace used for <xhtml:link> hreflang alternates.
+const xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// HreflangLink represents a language variant of a page declared with
+// <link rel="alternate" hreflang="...">, serialized in the sitemap as
+// <xhtml:link rel="alternate" hreflang="..." href="..."/>.
+type HreflangLink struct {
+	Rel  string `xml:"rel,attr" json:"-"`         // Always "alternate"
+	Lang string `xml:"hreflang,attr" json:"lang"` // Language/region code, or "x-default"
+	Href string `xml:"href,attr" json:"href"`     // Absolute URL of the language variant
+}
+
+// ExtractHreflang collects the language variants a page declares with
+// <link rel="alternate" hreflang="..." href="...">. Hrefs are returned as written, so
+// relative ones still need to be resolved against the page URL. Links without a
+// hreflang or href attribute, and exact duplicates, are skipped.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//
+// Returns:
+//   - []HreflangLink: The declared alternates in document order
+func ExtractHreflang(n *html.Node) []HreflangLink {
+	var alternates []HreflangLink
+	seen := make(map[HreflangLink]struct{})
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Link && hasRel(node, "alternate") {
+			alt := HreflangLink{
+				Rel:  "alternate",
+				Lang: strings.TrimSpace(attrValue(node, "hreflang")),
+				Href: strings.TrimSpace(attrValue(node, "href")),
+			}
+			if _, exists := seen[alt]; alt.Lang != "" && alt.Href != "" && !exists {
+				seen[alt] = struct{}{}
+				alternates = append(alternates, alt)
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(n)
+	return alternates
+}
+
+// extractAlternates returns a page's hreflang alternates with their hrefs resolved
+// against the page URL, dropping those that do not resolve to an http(s) URL.
+// Self-referencing alternates are kept, as Google recommends listing every variant
+// including the page itself.
+func extractAlternates(n *html.Node, pageURL string) []HreflangLink {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var alternates []HreflangLink
+	seen := make(map[HreflangLink]struct{})
+	for _, alt := range ExtractHreflang(n) {
+		href, ok := resolveResource(page, alt.Href)
+		if !ok {
+			continue
+		}
+		alt.Href = href
+		if _, exists := seen[alt]; !exists {
+			seen[alt] = struct{}{}
+			alternates = append(alternates, alt)
+		}
+	}
+	return alternates
+}
+
+// hasRel reports whether an element's space-separated rel attribute contains value,
+// compared case-insensitively.
+func hasRel(n *html.Node, value string) bool {
+	return slices.ContainsFunc(strings.Fields(attrValue(n, "rel")), func(rel string) bool {
+		return strings.EqualFold(rel, value)
+	})
+}
@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// imageNamespace is the XML namespace of Google's image sitemap extension.
+const imageNamespace = "http://www.google.com/schemas/sitemap-image/1.1"
+
+// Image represents an image embedded in a page, serialized as an <image:image>
+// child of the page's <url> entry in the image sitemap extension.
+type Image struct {
+	Loc     string `xml:"image:loc" json:"loc"`                             // Absolute URL of the image
+	Caption string `xml:"image:caption,omitempty" json:"caption,omitempty"` // Caption, taken from the alt attribute
+	Title   string `xml:"image:title,omitempty" json:"title,omitempty"`     // Title, taken from the title attribute
+}
+
+// ExtractImages collects the <img src> values of a page as absolute image URLs, along
+// with the alt text as caption and the title attribute as title. Only images hosted on
+// the same host as the page are returned; data URIs and images served from other hosts
+// are skipped. Duplicate sources are reported once.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//   - pageURL: Absolute URL of the page, used to resolve relative sources
+//
+// Returns:
+//   - []Image: Unique same-host images in document order
+func ExtractImages(n *html.Node, pageURL string) []Image {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var images []Image
+	seen := make(map[string]struct{})
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Img {
+			loc, ok := resolveResource(page, attrValue(node, "src"))
+			if _, exists := seen[loc]; ok && !exists && sameHost(loc, page) {
+				seen[loc] = struct{}{}
+				images = append(images, Image{
+					Loc:     loc,
+					Caption: strings.Join(strings.Fields(attrValue(node, "alt")), " "),
+					Title:   strings.Join(strings.Fields(attrValue(node, "title")), " "),
+				})
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(n)
+	return images
+}
+
+// resolveResource resolves an embedded resource reference (such as an image or video
+// source) against the page URL and reports whether it is a fetchable http(s) URL.
+// Data URIs and empty references are rejected.
+func resolveResource(page *url.URL, src string) (string, bool) {
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") {
+		return "", false
+	}
+
+	ref, err := url.Parse(src)
+	if err != nil {
+		return "", false
+	}
+	abs := page.ResolveReference(ref)
+
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return "", false
+	}
+	return abs.String(), true
+}
+
+// sameHost reports whether rawURL is served from the same host as page.
+func sameHost(rawURL string, page *url.URL) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && strings.EqualFold(u.Host, page.Host)
+}
@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncodeJSON converts links into an indented JSON array using the field names documented
+// on Link. It is the string-returning counterpart of WriteJSON, mirroring EncodeXML.
+//
+// Parameters:
+//   - links: Crawled links to encode
+//
+// Returns:
+//   - string: Complete JSON document
+//   - error: Any error that occurred during encoding
+func EncodeJSON(links []Link) (string, error) {
+	var sb strings.Builder
+	if err := WriteJSON(links, &sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteJSON writes links as a single indented JSON array, one object per URL, using the
+// field names documented on Link. An empty crawl produces an empty array rather than null,
+// so the output is always a valid document that scripts can parse without special cases.
+//
+// Parameters:
+//   - links: Crawled links including their depth, status and parent metadata
+//   - w: Destination for the JSON document
+//
+// Returns:
+//   - error: Any error that occurred during encoding or writing
+func WriteJSON(links []Link, w io.Writer) error {
+	if links == nil {
+		links = []Link{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // Keep '&' in query strings readable
+	if err := enc.Encode(links); err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+	return nil
+}
+
+// DecodeJSON reads a JSON array produced by WriteJSON or EncodeJSON back into links.
+// Encoding and then decoding a slice of links yields an equal slice, which makes the
+// JSON output suitable for caching crawl results between runs.
+//
+// Parameters:
+//   - r: Source of the JSON document
+//
+// Returns:
+//   - []Link: The decoded links
+//   - error: Any error that occurred while reading or decoding
+func DecodeJSON(r io.Reader) ([]Link, error) {
+	var links []Link
+	if err := json.NewDecoder(r).Decode(&links); err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+	return links, nil
+}
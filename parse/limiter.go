@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter decides when a request to a host may be sent. Pass one in CrawlOptions.Limiter
+// to apply a custom rate policy; it is consulted before every fetch, retries included.
+// Implementations must be safe for concurrent use by the crawl's workers.
+type Limiter interface {
+	// Wait blocks until a request to host may be sent, returning an error if ctx is
+	// cancelled first or the request can never be allowed.
+	Wait(ctx context.Context, host string) error
+}
+
+// PerHostLimiter is a Limiter keeping a separate token bucket for every host, so requests
+// to different hosts proceed independently while bursts to the same host are queued.
+type PerHostLimiter struct {
+	rps   rate.Limit // Tokens added to every bucket per second
+	burst int        // Capacity of every bucket
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter // Bucket of every host seen so far
+}
+
+// NewPerHostLimiter creates a PerHostLimiter allowing rps requests per second to each host,
+// with bursts of up to burst requests.
+//
+// Parameters:
+//   - rps: Sustained requests per second per host (must be positive)
+//   - burst: Requests a host may receive at once after being idle; values below 1 are treated as 1
+//
+// Returns:
+//   - *PerHostLimiter: A limiter with no hosts seen yet
+func NewPerHostLimiter(rps float64, burst int) *PerHostLimiter {
+	return &PerHostLimiter{
+		rps:      rate.Limit(rps),
+		burst:    max(burst, 1),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Wait blocks until host's bucket has a token, or until ctx is cancelled.
+func (l *PerHostLimiter) Wait(ctx context.Context, host string) error {
+	return l.limiter(host).Wait(ctx)
+}
+
+// Allow reports whether a request to host may be sent right now, taking a token from
+// host's bucket if so. It never blocks, for callers that would rather skip or requeue a
+// request than wait for it.
+func (l *PerHostLimiter) Allow(host string) bool {
+	return l.limiter(host).Allow()
+}
+
+// limiter returns the bucket of host, creating it on first use.
+func (l *PerHostLimiter) limiter(host string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim, ok := l.limiters[host]
+	if !ok {
+		lim = rate.NewLimiter(l.rps, l.burst)
+		l.limiters[host] = lim
+	}
+	return lim
+}
+
+// hostOf returns the host of rawURL, or rawURL itself if it cannot be parsed, so that
+// such URLs still share a bucket.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
@@ -0,0 +1,156 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// requestTimes serves a testSite and records when every page other than robots.txt
+// was requested.
+type requestTimes struct {
+	site  testSite
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (rt *requestTimes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/robots.txt" {
+		rt.mu.Lock()
+		rt.times = append(rt.times, time.Now())
+		rt.mu.Unlock()
+	}
+	rt.site.ServeHTTP(w, r)
+}
+
+// serve starts the site and stops it when the test ends.
+func (rt *requestTimes) serve(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// minGap returns the shortest interval between two of the recorded requests.
+func (rt *requestTimes) minGap(t *testing.T) time.Duration {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if len(rt.times) < 2 {
+		t.Fatalf("only %d requests recorded", len(rt.times))
+	}
+	times := slices.Clone(rt.times)
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	gap := times[1].Sub(times[0])
+	for i := 2; i < len(times); i++ {
+		gap = min(gap, times[i].Sub(times[i-1]))
+	}
+	return gap
+}
+
+// spacingSite is a site of a start page linking to five others.
+func spacingSite() testSite {
+	return testSite{
+		"/":  `<a href="/1">1</a> <a href="/2">2</a> <a href="/3">3</a> <a href="/4">4</a> <a href="/5">5</a>`,
+		"/1": `<p>1</p>`, "/2": `<p>2</p>`, "/3": `<p>3</p>`, "/4": `<p>4</p>`, "/5": `<p>5</p>`,
+	}
+}
+
+// spacingSlack absorbs the difference between the moment a request is sent and the
+// moment the server sees it.
+const spacingSlack = 5 * time.Millisecond
+
+func TestPerHostRateLimitSpacesRequests(t *testing.T) {
+	const rps = 20
+	interval := time.Second / rps
+	first, second := &requestTimes{site: spacingSite()}, &requestTimes{site: spacingSite()}
+	firstSrv, secondSrv := first.serve(t), second.serve(t)
+
+	_, err := CrawlBFS(context.Background(), []Link{{Href: firstSrv.URL + "/"}, {Href: secondSrv.URL + "/"}},
+		WithPerHostRateLimit(rps, 1), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+
+	for name, rt := range map[string]*requestTimes{"first": first, "second": second} {
+		if gap := rt.minGap(t); gap < interval-spacingSlack {
+			t.Errorf("%s host: requests %s apart, want at least %s", name, gap, interval)
+		}
+	}
+}
+
+func TestCrawlDelaySpacesRequestsToOtherHost(t *testing.T) {
+	interval := 50 * time.Millisecond
+	home := testSite{"/": `<p>Home</p>`}.serve(t)
+	site := spacingSite()
+	site["/robots.txt"] = "User-agent: *\nCrawl-delay: 0.05\n"
+	other := &requestTimes{site: site}
+	otherSrv := other.serve(t)
+
+	robots, err := NewRobotsFilter(context.Background(), home.URL, http.DefaultClient, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("NewRobotsFilter: %v", err)
+	}
+	_, err = CrawlBFS(context.Background(), []Link{{Href: home.URL + "/"}, {Href: otherSrv.URL + "/"}},
+		WithRobotsFilter(robots), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+
+	if gap := other.minGap(t); gap < interval-spacingSlack {
+		t.Errorf("requests %s apart, want at least the Crawl-delay of %s", gap, interval)
+	}
+}
+
+// refusingLimiter lets the first allowed requests through and refuses every later one.
+type refusingLimiter struct {
+	mu      sync.Mutex
+	allowed int
+}
+
+func (l *refusingLimiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.allowed == 0 {
+		return errors.New("rate limit refused")
+	}
+	l.allowed--
+	return nil
+}
+
+func TestRefusedPagesAreNotCountedAsDepthSkips(t *testing.T) {
+	srv := testSite{
+		"/":  `<a href="/a">A</a> <a href="/b">B</a>`,
+		"/a": `<a href="/c">C</a>`,
+		"/b": `<p>B</p>`,
+		"/c": `<p>C</p>`,
+	}.serve(t)
+
+	report := &Report{}
+	result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}}, WithOptions(CrawlOptions{
+		Limiter: &refusingLimiter{allowed: 2},
+		Report:  report,
+	}), WithMaxDepth(2), WithConcurrency(1))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+
+	if result.PagesVisited != 2 || result.PagesFailed != 0 {
+		t.Errorf("fetched %d pages, %d failed; want / and /a fetched", result.PagesVisited, result.PagesFailed)
+	}
+	if result.PagesSkipped != 1 || result.PagesAbandoned != 1 {
+		t.Errorf("skipped %d pages, abandoned %d; want /c skipped by depth and /b abandoned", result.PagesSkipped, result.PagesAbandoned)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].URL != srv.URL+"/b" {
+		t.Errorf("Errors = %v, want the refused /b", result.Errors)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].URL != srv.URL+"/b" {
+		t.Errorf("Report.Failures = %v, want the refused /b", report.Failures)
+	}
+	if len(report.Timings) != 2 {
+		t.Errorf("Report.Timings = %v, want the 2 fetched pages", report.Timings)
+	}
+}
@@ -0,0 +1,145 @@
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// MaxLocLength is the maximum length of a <loc> value allowed by the sitemap protocol,
+// counted after XML entity escaping.
+const MaxLocLength = 2048
+
+// Policies for URLs longer than MaxLocLength, as accepted by EnforceLocLength.
+const (
+	LongURLWarn     = "warn"     // Keep the URL and log a warning
+	LongURLSkip     = "skip"     // Leave the URL out of the sitemap
+	LongURLTruncate = "truncate" // Shorten the URL to the limit
+)
+
+// LongURLPolicies lists the policies accepted by EnforceLocLength.
+var LongURLPolicies = []string{LongURLWarn, LongURLSkip, LongURLTruncate}
+
+// SanitizeLoc prepares a URL for a sitemap <loc> element. The URL is parsed and
+// re-serialized so that its path, query and fragment are percent-encoded as RFC 3986
+// requires: spaces, quotes, angle brackets and raw UTF-8 are escaped, while existing
+// escapes and the query's & and = separators are preserved. XML entity escaping of
+// characters such as & is left to the XML encoder.
+//
+// Parameters:
+//   - raw: The URL as discovered during the crawl
+//
+// Returns:
+//   - string: The encoded URL
+//   - error: The URL is malformed or is not an absolute http(s) URL with a host
+func SanitizeLoc(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid URL %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q: missing host", raw)
+	}
+
+	// The URL type re-encodes the path on output but keeps the raw query verbatim
+	u.RawQuery = escapeQuery(u.RawQuery)
+	return u.String(), nil
+}
+
+// escapeQuery percent-encodes every byte of a raw query string that may not appear
+// unescaped in a URL, leaving valid escapes and reserved delimiters untouched.
+func escapeQuery(query string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '%' && i+2 < len(query) && isHex(query[i+1]) && isHex(query[i+2]):
+			sb.WriteByte(c)
+		case c == '%' || c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0x0f])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// EscapedLocLength returns the length loc will have inside a <loc> element, after XML
+// entity escaping turns characters such as & into &amp;.
+func EscapedLocLength(loc string) int {
+	var cw countingWriter
+	xml.EscapeText(&cw, []byte(loc))
+	return cw.n
+}
+
+// EnforceLocLength applies a policy to links whose sanitized URL exceeds MaxLocLength
+// once escaped. Links with malformed URLs are passed through untouched, since the XML
+// encoder skips them anyway.
+//
+// Parameters:
+//   - links: The links to check
+//   - policy: LongURLWarn, LongURLSkip or LongURLTruncate
+//
+// Returns:
+//   - []Link: The links to encode; truncated links carry the shortened, sanitized URL
+//   - []string: The original URLs that exceeded the limit, in link order
+//   - error: An unknown policy
+func EnforceLocLength(links []Link, policy string) ([]Link, []string, error) {
+	if !slices.Contains(LongURLPolicies, policy) {
+		return nil, nil, fmt.Errorf("invalid long URL policy %q (expected one of %v)", policy, LongURLPolicies)
+	}
+
+	kept := make([]Link, 0, len(links))
+	var offenders []string
+	for _, link := range links {
+		loc, err := SanitizeLoc(link.Href)
+		if err != nil || EscapedLocLength(loc) <= MaxLocLength {
+			kept = append(kept, link)
+			continue
+		}
+
+		offenders = append(offenders, link.Href)
+		currentLogger().Warn("URL exceeds the sitemap length limit", "url", link.Href, "policy", policy)
+		switch policy {
+		case LongURLWarn:
+			kept = append(kept, link)
+		case LongURLTruncate:
+			link.Href = truncateLoc(loc, MaxLocLength)
+			kept = append(kept, link)
+		}
+	}
+	return kept, offenders, nil
+}
+
+// truncateLoc shortens a sanitized URL so that its escaped length is at most limit,
+// never cutting through a multi-byte character or a %XX escape.
+func truncateLoc(loc string, limit int) string {
+	size, cut := 0, 0
+	for i, r := range loc {
+		size += EscapedLocLength(string(r))
+		if size > limit {
+			break
+		}
+		cut = i + len(string(r))
+	}
+
+	// Back up to before a %XX escape the cut would split
+	if i := strings.LastIndexByte(loc[:cut], '%'); i >= 0 && cut-i < 3 {
+		cut = i
+	}
+	return loc[:cut]
+}
@@ -0,0 +1,37 @@
+package parse
+
+import "testing"
+
+func TestSanitizeLoc(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "https://example.com/about", "https://example.com/about", false},
+		{"ampersand in query", "https://example.com/search?q=a&page=2", "https://example.com/search?q=a&page=2", false},
+		{"space in path", "https://example.com/my page", "https://example.com/my%20page", false},
+		{"space in query", "https://example.com/search?q=two words", "https://example.com/search?q=two%20words", false},
+		{"quotes and brackets in query", `https://example.com/?q="<b>"`, "https://example.com/?q=%22%3Cb%3E%22", false},
+		{"unicode path", "https://example.com/café/naïve", "https://example.com/caf%C3%A9/na%C3%AFve", false},
+		{"unicode query", "https://example.com/?city=Zürich", "https://example.com/?city=Z%C3%BCrich", false},
+		{"existing escapes kept", "https://example.com/a%20b?q=x%26y", "https://example.com/a%20b?q=x%26y", false},
+		{"surrounding whitespace", "  https://example.com/  ", "https://example.com/", false},
+		{"relative", "/about", "", true},
+		{"other scheme", "mailto:team@example.com", "", true},
+		{"missing host", "https:///about", "", true},
+		{"malformed", "https://example.com/%zz", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeLoc(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SanitizeLoc(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SanitizeLoc(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
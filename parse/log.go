@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Logger receives the diagnostic output of the parse package, such as pages that could
+// not be fetched. Each method takes a message followed by alternating key/value pairs
+// describing it, e.g. Warn("fetch failed", "url", u, "err", err).
+type Logger interface {
+	Debug(msg string, keyvals ...any)
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+}
+
+// noopLogger discards everything; it is the default Logger.
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...any) {}
+func (noopLogger) Info(string, ...any)  {}
+func (noopLogger) Warn(string, ...any)  {}
+func (noopLogger) Error(string, ...any) {}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = noopLogger{}
+)
+
+// SetLogger installs l as the destination of the package's diagnostic output.
+// Passing nil restores the default, which discards everything.
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if l == nil {
+		l = noopLogger{}
+	}
+	logger = l
+}
+
+// currentLogger returns the installed Logger.
+func currentLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+// StderrLogger is a Logger that writes one line of structured key=value pairs per
+// message to stderr, for example:
+//
+//	time=2024-05-01T10:00:00.000Z level=WARN msg="fetch failed" url=https://example.com/x err="..."
+//
+// Messages of every level, including Debug, are written.
+type StderrLogger struct {
+	logger *slog.Logger
+}
+
+// NewStderrLogger creates a StderrLogger.
+func NewStderrLogger() *StderrLogger {
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return &StderrLogger{logger: slog.New(handler)}
+}
+
+// Debug implements Logger.
+func (l *StderrLogger) Debug(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, keyvals...)
+}
+
+// Info implements Logger.
+func (l *StderrLogger) Info(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+// Warn implements Logger.
+func (l *StderrLogger) Warn(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+// Error implements Logger.
+func (l *StderrLogger) Error(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, keyvals...)
+}
@@ -0,0 +1,18 @@
+package parse
+
+import "time"
+
+// Metrics receives the progress of a crawl as it happens, for export to a monitoring
+// system such as Prometheus. Pass one in CrawlOptions.Metrics. Implementations must be
+// safe for concurrent use by the crawl's workers.
+type Metrics interface {
+	// Queued reports that n more pages are waiting to be fetched, or with a negative n
+	// that pages were taken off the queue without being fetched.
+	Queued(n int)
+
+	// Visiting reports that the fetch of a page at depth is starting, taking it off the queue.
+	Visiting(depth int)
+
+	// Fetched reports that a fetch took d, retries included, and whether it failed.
+	Fetched(d time.Duration, failed bool)
+}
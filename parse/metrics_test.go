@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingMetrics is a Metrics recording the events it receives.
+type countingMetrics struct {
+	mu      sync.Mutex
+	queued  int
+	fetched int
+	failed  int
+}
+
+func (m *countingMetrics) Queued(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queued += n
+}
+
+func (m *countingMetrics) Visiting(depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queued--
+}
+
+func (m *countingMetrics) Fetched(d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetched++
+	if failed {
+		m.failed++
+	}
+}
+
+func TestMetricsMatchCrawlResult(t *testing.T) {
+	srv := testSite{
+		"/":          `<a href="/guide.pdf">Guide</a> <a href="/logo.pdf">Logo</a> <a href="/missing">Gone</a> <a href="/about">About</a>`,
+		"/about":     `<p>About</p>`,
+		"/guide.pdf": "%PDF-1.4",
+		"/logo.pdf":  "%PDF-1.4",
+	}.serve(t)
+
+	metrics := &countingMetrics{}
+	result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}},
+		WithOptions(CrawlOptions{Metrics: metrics}), WithConcurrency(4))
+	if err != nil {
+		t.Fatalf("CrawlBFS: %v", err)
+	}
+
+	if result.PagesFailed != 1 {
+		t.Errorf("PagesFailed = %d, want 1 (only /missing)", result.PagesFailed)
+	}
+	if metrics.failed != result.PagesFailed {
+		t.Errorf("metrics counted %d failed fetches, CrawlResult %d", metrics.failed, result.PagesFailed)
+	}
+	if metrics.fetched != result.PagesVisited {
+		t.Errorf("metrics counted %d fetches, CrawlResult %d", metrics.fetched, result.PagesVisited)
+	}
+	if len(result.NonHTML) != 2 {
+		t.Errorf("NonHTML = %v, want the two PDFs", result.NonHTML)
+	}
+	if metrics.queued != 0 {
+		t.Errorf("%d pages left queued after a complete crawl", metrics.queued)
+	}
+}
+
+// cancelingMetrics counts like countingMetrics and cancels the crawl once a page is
+// queued after the first one.
+type cancelingMetrics struct {
+	countingMetrics
+	cancel context.CancelFunc
+	pages  int
+}
+
+func (m *cancelingMetrics) Queued(n int) {
+	m.countingMetrics.Queued(n)
+	if m.pages += max(n, 0); m.pages > 1 {
+		m.cancel()
+	}
+}
+
+func TestMetricsQueueEmptyAfterInterruption(t *testing.T) {
+	srv := testSite{
+		"/":      `<a href="/about">About</a> <a href="/team">Team</a>`,
+		"/about": `<p>About</p>`,
+		"/team":  `<p>Team</p>`,
+	}.serve(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	metrics := &cancelingMetrics{cancel: cancel}
+	result, err := CrawlDFS(ctx, []Link{{Href: srv.URL + "/"}}, WithOptions(CrawlOptions{Metrics: metrics}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CrawlDFS error = %v, want it cancelled", err)
+	}
+	if result.PagesVisited != 1 {
+		t.Errorf("fetched %d pages, want only the start page", result.PagesVisited)
+	}
+	if metrics.queued != 0 {
+		t.Errorf("%d pages left queued after an interrupted crawl", metrics.queued)
+	}
+}
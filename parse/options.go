@@ -0,0 +1,215 @@
+package parse
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// DefaultMaxDepth is the crawl depth used when no WithMaxDepth option is given, matching
+// the -depth default of the command-line tool.
+const DefaultMaxDepth = 3
+
+// CrawlOption configures a crawl started by CrawlBFS or CrawlDFS. Options are applied in
+// the order they are passed, so a later option overrides an earlier one.
+type CrawlOption func(*crawlConfig)
+
+// crawlConfig collects the settings of a single crawl from its options.
+type crawlConfig struct {
+	maxDepth int          // Maximum depth to crawl
+	client   *http.Client // HTTP client, nil to build one with NewHTTPClient(opts)
+	opts     CrawlOptions // Every other crawl setting
+	stream   io.Writer    // Destination of the streamed sitemap, nil to collect the links
+}
+
+// newCrawlConfig applies options on top of the defaults: DefaultMaxDepth, a client built
+// from the final settings and the zero CrawlOptions.
+func newCrawlConfig(options []CrawlOption) crawlConfig {
+	cfg := crawlConfig{maxDepth: DefaultMaxDepth}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// WithOptions replaces every setting held in CrawlOptions at once, for callers that build
+// a CrawlOptions value anyway, such as from command-line flags. Pass it before the more
+// specific options, which would otherwise be overwritten.
+//
+// Parameters:
+//   - opts: The crawl settings to use
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithOptions(opts CrawlOptions) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts = opts
+	}
+}
+
+// WithMaxDepth sets how many links deep the crawl goes: 0 only visits the seeds, 1 also
+// the pages they link to, and so on. Without it the crawl goes DefaultMaxDepth levels deep.
+//
+// Parameters:
+//   - n: Maximum depth to crawl
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithMaxDepth(n int) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.maxDepth = n
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for every request of the crawl. Without it a
+// client is built with NewHTTPClient from the other settings.
+//
+// Parameters:
+//   - c: HTTP client for making requests
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithHTTPClient(c *http.Client) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.client = c
+	}
+}
+
+// StreamTo writes the sitemap to w with EncodeXMLStream while the crawl runs, each link
+// as soon as it is listed, instead of collecting the links in CrawlResult.Links, which
+// stays empty. Memory use then no longer grows with the number of listed pages, though
+// the visited URLs are still tracked. Checkpoints record no results in this mode.
+//
+// Parameters:
+//   - w: Destination for the XML sitemap
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func StreamTo(w io.Writer) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.stream = w
+	}
+}
+
+// WithConcurrency sets CrawlOptions.Concurrency, the number of pages fetched in parallel.
+//
+// Parameters:
+//   - n: Number of workers; values below 1 are treated as 1
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithConcurrency(n int) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.Concurrency = n
+	}
+}
+
+// WithDelay sets CrawlOptions.Delay, the minimum pause between the fetches of each worker.
+//
+// Parameters:
+//   - d: Politeness delay; zero crawls at full speed
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithDelay(d time.Duration) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.Delay = d
+	}
+}
+
+// WithUserAgent sets CrawlOptions.UserAgent, sent with every request.
+//
+// Parameters:
+//   - ua: User-Agent header value; empty selects DefaultUserAgent
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithUserAgent(ua string) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.UserAgent = ua
+	}
+}
+
+// WithPerHostRateLimit sets CrawlOptions.Limiter to a PerHostLimiter, so that requests
+// to every host, such as the subdomains a site links to, are rate-limited independently.
+//
+// Parameters:
+//   - rps: Sustained requests per second per host (must be positive)
+//   - burst: Requests a host may receive at once after being idle; values below 1 are treated as 1
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithPerHostRateLimit(rps float64, burst int) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.Limiter = NewPerHostLimiter(rps, burst)
+	}
+}
+
+// WithRobotsFilter sets CrawlOptions.Robots, consulted before any URL is enqueued.
+//
+// Parameters:
+//   - f: Parsed robots.txt rules, or nil to crawl without them
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithRobotsFilter(f *RobotsFilter) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.Robots = f
+	}
+}
+
+// WithExcludePatterns adds to CrawlOptions.ExcludePatterns; URLs matching any of them are
+// neither crawled nor listed.
+//
+// Parameters:
+//   - patterns: Regular expressions matched against every discovered URL
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithExcludePatterns(patterns ...*regexp.Regexp) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.ExcludePatterns = append(cfg.opts.ExcludePatterns, patterns...)
+	}
+}
+
+// WithIncludePatterns adds to CrawlOptions.IncludePatterns; when any are set, only URLs
+// whose path matches one of them are crawled and listed.
+//
+// Parameters:
+//   - patterns: Regular expressions matched against the path of every discovered URL
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithIncludePatterns(patterns ...*regexp.Regexp) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.IncludePatterns = append(cfg.opts.IncludePatterns, patterns...)
+	}
+}
+
+// WithLinkFilter sets CrawlOptions.LinkFilter, a custom predicate every discovered URL
+// must satisfy to be crawled and listed.
+//
+// Parameters:
+//   - filter: Returns false for URLs to leave out
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithLinkFilter(filter func(rawURL string) bool) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.LinkFilter = filter
+	}
+}
+
+// WithMaxPages sets CrawlOptions.MaxPages, the budget of pages fetched by the crawl.
+//
+// Parameters:
+//   - n: Maximum number of pages to fetch; zero means no limit
+//
+// Returns:
+//   - CrawlOption: The option to pass to CrawlBFS or CrawlDFS
+func WithMaxPages(n int) CrawlOption {
+	return func(cfg *crawlConfig) {
+		cfg.opts.MaxPages = n
+	}
+}
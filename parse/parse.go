@@ -4,10 +4,15 @@
 package parse
 
 import (
+	"bufio"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"iter"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
@@ -15,24 +20,85 @@ import (
 )
 
 // Link represents an HTML anchor element with its URL and text content.
-// This structure is used internally during the crawling process.
+// This structure is used internally during the crawling process, where CrawlBFS
+// enriches it with metadata about how and where the page was found.
+//
+// The JSON field names are part of the -format json output and must stay stable:
+//   - url: absolute URL of the page
+//   - text: anchor text of the link that led to the page
+//   - depth: number of links followed from the start URL (0 for the start URL)
+//   - status: HTTP status code of the fetch, or 0 if the page was not fetched
+//   - parent: URL of the page the link was found on, empty for the start URL
+//   - title: text of the page's <title>, empty if the page was not fetched
+//   - description: content of the page's <meta name="description">, empty if none
+//   - content_type: Content-Type header of the fetch, empty if the page was not fetched
+//   - last_modified: W3C datetime from the Last-Modified header, empty if unknown
+//   - changefreq: sitemap change frequency hint, empty if unset
+//   - priority: sitemap priority hint, null if unset
+//   - images: same-host images found on the page, omitted when none were collected
+//   - videos: videos embedded in the page, omitted when none were collected
+//   - alternates: hreflang language variants declared by the page, omitted when none
 type Link struct {
-	Href string // The URL/href attribute of the link
-	Text string // The visible text content of the link
+	Href        string         `json:"url"`                  // The URL/href attribute of the link
+	Text        string         `json:"text"`                 // The visible text content of the link
+	Depth       int            `json:"depth"`                // Crawl depth at which the link was discovered
+	StatusCode  int            `json:"status"`               // HTTP status code returned when the page was fetched
+	Parent      string         `json:"parent"`               // URL of the page containing the link
+	Title       string         `json:"title"`                // Text of the fetched page's <title>
+	Description string         `json:"description"`          // The fetched page's meta description
+	ContentType string         `json:"content_type"`         // Content-Type of the fetched page
+	LastMod     string         `json:"last_modified"`        // W3C datetime from the page's Last-Modified header, if any
+	ChangeFreq  string         `json:"changefreq"`           // Optional sitemap change frequency hint
+	Priority    *float64       `json:"priority"`             // Optional sitemap priority hint, nil if unset
+	Images      []Image        `json:"images,omitempty"`     // Images embedded in the page, for the image extension
+	Videos      []Video        `json:"videos,omitempty"`     // Videos embedded in the page, for the video extension
+	Alternates  []HreflangLink `json:"alternates,omitempty"` // Language variants declared by the page
 }
 
 // Urlset represents the root element of an XML sitemap according to the sitemap protocol.
 // It contains the XML namespace and a collection of URL entries.
 type Urlset struct {
-	XMLName xml.Name `xml:"urlset"`                                    // Root XML element name
-	Xmlns   string   `xml:"xmlns,attr"`                               // XML namespace attribute
-	Urls    []Url    `xml:"url"`                                      // Collection of URL entries
+	XMLName    xml.Name `xml:"urlset"`                     // Root XML element name
+	Xmlns      string   `xml:"xmlns,attr"`                 // XML namespace attribute
+	XmlnsImage string   `xml:"xmlns:image,attr,omitempty"` // Image extension namespace, only set when images exist
+	XmlnsVideo string   `xml:"xmlns:video,attr,omitempty"` // Video extension namespace, only set when videos exist
+	XmlnsXhtml string   `xml:"xmlns:xhtml,attr,omitempty"` // XHTML namespace, only set when hreflang alternates exist
+	Urls       []Url    `xml:"url"`                        // Collection of URL entries
 }
 
+// MaxURLsPerSitemap is the maximum number of URLs a single sitemap file may contain
+// according to the sitemap protocol. Larger sites must be split across several files
+// that are referenced from a sitemap index.
+const MaxURLsPerSitemap = 50000
+
+// sitemapNamespace is the XML namespace shared by sitemap and sitemap index documents.
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
 // Url represents a single URL entry in the XML sitemap.
-// Each entry contains the location (URL) of a page on the website.
+// Each entry contains the location (URL) of a page on the website and the optional
+// freshness hints defined by the protocol. Optional elements are omitted when empty.
 type Url struct {
-	Loc string `xml:"loc"` // The URL location of the page
+	Comment    string         `xml:",comment"`             // Page title written by WriteXMLExtended; not part of the protocol
+	Loc        string         `xml:"loc"`                  // The URL location of the page
+	LastMod    string         `xml:"lastmod,omitempty"`    // Last modification date in W3C datetime format
+	ChangeFreq string         `xml:"changefreq,omitempty"` // Expected change frequency (daily, weekly, ...)
+	Priority   *float64       `xml:"priority,omitempty"`   // Relative priority between 0.0 and 1.0, nil if unset
+	Images     []Image        `xml:"image:image"`          // Images on the page (image sitemap extension)
+	Videos     []Video        `xml:"video:video"`          // Videos on the page (video sitemap extension)
+	Alternates []HreflangLink `xml:"xhtml:link"`           // Language variants of the page
+}
+
+// Sitemapindex represents the root element of a sitemap index file, which references
+// multiple sitemap files when a site exceeds the per-file limits of the protocol.
+type Sitemapindex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"` // Root XML element name
+	Xmlns    string         `xml:"xmlns,attr"`   // XML namespace attribute
+	Sitemaps []SitemapEntry `xml:"sitemap"`      // Collection of child sitemap references
+}
+
+// SitemapEntry represents a single child sitemap reference inside a sitemap index.
+type SitemapEntry struct {
+	Loc string `xml:"loc"` // Absolute URL of the child sitemap file
 }
 
 // FetchAndParse retrieves an HTML document from the specified URL and parses it into a DOM tree.
@@ -40,78 +106,301 @@ type Url struct {
 // that can be traversed to extract links and other content.
 //
 // Parameters:
+//   - ctx: Context whose cancellation aborts the request, even while it is in flight
 //   - url: The URL to fetch and parse
 //   - client: HTTP client with configured timeout and other settings
+//   - opts: Request settings such as the User-Agent; the zero value uses the defaults
+//
+// A response that is not HTML, judged by its Content-Type or, without one, by its first
+// bytes, is not parsed; the error then wraps ErrNotHTML.
 //
 // Returns:
 //   - *html.Node: Root node of the parsed HTML document
 //   - error: Any error that occurred during fetching or parsing
-func FetchAndParse(url string, client *http.Client) (*html.Node, error) {
+func FetchAndParse(ctx context.Context, url string, client *http.Client, opts CrawlOptions) (*html.Node, error) {
+	page, err := fetchPage(ctx, url, client, opts)
+	return page.doc, err
+}
+
+// FetchStatus behaves like FetchAndParse but also reports the HTTP status code, and treats
+// a non-200 response as a result rather than an error, so callers can tell a 404 from a
+// network failure. The document is only parsed for a 200 response.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the request, even while it is in flight
+//   - url: The URL to fetch and parse
+//   - client: HTTP client with configured timeout and other settings
+//   - opts: Request settings such as the User-Agent; the zero value uses the defaults
+//
+// Returns:
+//   - int: HTTP status code of the final response, 0 if no response was received
+//   - *html.Node: Root node of the parsed HTML document, nil unless the status is 200
+//   - error: Any error that occurred during fetching or parsing, other than the status
+func FetchStatus(ctx context.Context, url string, client *http.Client, opts CrawlOptions) (int, *html.Node, error) {
+	page, err := fetchPage(ctx, url, client, opts)
+	if err != nil && page.status != 0 && page.status != http.StatusOK {
+		return page.status, nil, nil
+	}
+	return page.status, page.doc, err
+}
+
+// fetchedPage holds everything the crawler needs from a single HTTP fetch.
+type fetchedPage struct {
+	doc         *html.Node  // Parsed document, nil unless the fetch succeeded
+	header      http.Header // Response headers, nil if no response was received
+	status      int         // HTTP status code, 0 if no response was received
+	location    string      // URL of the final response after redirects, empty if none was received
+	contentType string      // Content-Type of the response, sniffed from a 200 body without one
+	size        int64       // Content-Length of the response, -1 if unknown
+}
+
+// fetchPage performs the work behind FetchAndParse and additionally returns the response
+// status and headers, which the crawler uses to record metadata such as Last-Modified.
+// The status is reported even when the fetch fails because of a non-200 response. With
+// opts.Cache, a page that did not change since it was cached is reported with status 304
+// and no document, without an error.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the request
+//   - url: The URL to fetch and parse
+//   - client: HTTP client with configured timeout and other settings
+//   - opts: Request settings such as the User-Agent
+//
+// Returns:
+//   - fetchedPage: Parsed document and response metadata
+//   - error: Any error that occurred during fetching or parsing
+func fetchPage(ctx context.Context, url string, client *http.Client, opts CrawlOptions) (fetchedPage, error) {
+	var page fetchedPage
+
 	// Create a new HTTP GET request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := newRequest(ctx, "GET", url, client, opts)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return nil, fmt.Errorf("creating request for URL %s: %w", url, err)
+		return page, err
 	}
 
-	// Set User-Agent header to avoid being blocked by websites that reject bot requests
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; SitemapBuilder/1.0)")
+	// Let the server skip sending a page that did not change since it was cached
+	opts.Cache.setConditional(req, url)
 
 	// Execute the HTTP request
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("fetching URL %s: %w", url, err)
+		return page, fmt.Errorf("fetching URL %s: %w", url, err)
 	}
 	defer resp.Body.Close() // Ensure response body is closed to prevent resource leaks
+	page.status = resp.StatusCode
+	page.header = resp.Header
+	page.location = resp.Request.URL.String()
+	page.contentType = resp.Header.Get("Content-Type")
+	page.size = resp.ContentLength
+
+	// An unchanged cached page comes without a body; the caller takes it from the cache
+	if resp.StatusCode == http.StatusNotModified && opts.Cache != nil {
+		return page, nil
+	}
 
 	// Check for successful HTTP status code
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fetching URL %s: received status code %d", url, resp.StatusCode)
+		return page, fmt.Errorf("fetching URL %s: received status code %d", url, resp.StatusCode)
+	}
+
+	// Only parse HTML, identifying a response without a Content-Type by its first bytes;
+	// closing the body of anything else unread saves downloading it
+	body := bufio.NewReader(resp.Body)
+	if page.contentType == "" {
+		page.contentType = sniffContentType(body)
+	}
+	if !isHTML(page.contentType) {
+		return page, fmt.Errorf("fetching URL %s: %w (%s)", url, ErrNotHTML, page.contentType)
 	}
 
 	// Parse the HTML response body into a DOM tree
-	doc, err := html.Parse(resp.Body)
+	doc, err := html.Parse(body)
+	if err != nil {
+		return page, fmt.Errorf("parsing HTML from %s: %w", url, err)
+	}
+	page.doc = doc
+
+	return page, nil
+}
+
+// newRequest creates a request carrying the crawl's custom headers, User-Agent and cookies.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the request
+//   - method: HTTP method, such as GET or HEAD
+//   - url: The URL to request
+//   - client: Client that will send the request; cookies are left to its jar if it has one
+//   - opts: Request settings such as the User-Agent
+//
+// Returns:
+//   - *http.Request: The prepared request
+//   - error: An invalid URL or forbidden custom header
+func newRequest(ctx context.Context, method, url string, client *http.Client, opts CrawlOptions) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
-		fmt.Println("Error parsing HTML:", err)
-		return nil, fmt.Errorf("parsing HTML from %s: %w", url, err)
+		return nil, fmt.Errorf("creating request for URL %s: %w", url, err)
+	}
+
+	// Apply custom headers first so the configured User-Agent, which robots.txt rules
+	// are matched against, always wins
+	if err := ValidateHeaders(opts.Headers); err != nil {
+		return nil, fmt.Errorf("creating request for URL %s: %w", url, err)
+	}
+	for key, value := range opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// Set User-Agent header to avoid being blocked by websites that reject bot requests
+	req.Header.Set("User-Agent", opts.userAgent())
+
+	// Answer a Basic Auth prompt up front instead of waiting to be challenged
+	if opts.BasicAuth != nil {
+		req.SetBasicAuth(opts.BasicAuth.Username, opts.BasicAuth.Password)
+	}
+
+	// Carry session cookies; a client with a cookie jar already sends them from the jar
+	if client.Jar == nil {
+		for _, cookie := range opts.Cookies {
+			req.AddCookie(cookie)
+		}
 	}
 
-	return doc, nil
+	return req, nil
 }
 
-// extractText recursively extracts and concatenates all text content from an HTML node and its children.
+// ExtractText recursively extracts and concatenates all text content from an HTML node and its children.
 // It traverses the DOM tree depth-first, collecting text from all text nodes and normalizing whitespace.
-// This function is used to get the visible text content of anchor elements for link descriptions.
+// This function is used to get the visible text content of anchor elements for link descriptions,
+// and accepts a whole document as well as any element within it.
 //
 // Parameters:
 //   - n: The HTML node to extract text from
 //
 // Returns:
 //   - string: Normalized text content with excess whitespace removed
-func extractText(n *html.Node) string {
+func ExtractText(n *html.Node) string {
 	// Base case: if this is a text node, return its content
 	if n.Type == html.TextNode {
 		return n.Data
 	}
 
-	// Skip non-element nodes (comments, etc.)
-	if n.Type != html.ElementNode {
+	// Skip nodes that cannot contain text (comments, doctypes, etc.)
+	if n.Type != html.ElementNode && n.Type != html.DocumentNode {
 		return ""
 	}
 
 	// Recursively collect text from all child nodes
 	var sb strings.Builder
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		sb.WriteString(extractText(c))
+		sb.WriteString(ExtractText(c))
 	}
 
 	// Normalize whitespace: split on any whitespace and rejoin with single spaces
 	return strings.Join(strings.Fields(sb.String()), " ")
 }
 
-// ExtractLinks traverses an HTML document tree and extracts all internal links (anchor elements).
-// It performs a depth-first traversal of the DOM, identifying anchor tags with href attributes
-// that point to internal pages within the same domain. Duplicate links are automatically filtered out.
+// ExtractTitle returns the whitespace-normalized text of the document's first <title>
+// element, or an empty string if the document has none.
+//
+// Parameters:
+//   - doc: Root HTML node of the page
+//
+// Returns:
+//   - string: The page title
+func ExtractTitle(doc *html.Node) string {
+	title := findElement(doc, atom.Title)
+	if title == nil {
+		return ""
+	}
+	return ExtractText(title)
+}
+
+// ExtractMetaDescription returns the whitespace-normalized content of the first
+// <meta name="description"> tag in the document's <head>, matching the name
+// case-insensitively, or an empty string if there is none.
+//
+// Parameters:
+//   - doc: Root HTML node of the page
+//
+// Returns:
+//   - string: The page description
+func ExtractMetaDescription(doc *html.Node) string {
+	head := findElement(doc, atom.Head)
+	if head == nil {
+		return ""
+	}
+
+	var walk func(*html.Node) (string, bool)
+	walk = func(node *html.Node) (string, bool) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Meta &&
+			strings.EqualFold(strings.TrimSpace(attrValue(node, "name")), "description") {
+			return strings.Join(strings.Fields(attrValue(node, "content")), " "), true
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if description, ok := walk(child); ok {
+				return description, true
+			}
+		}
+		return "", false
+	}
+
+	description, _ := walk(head)
+	return description
+}
+
+// attrValue returns the value of the named attribute of an element, or an empty
+// string if the attribute is not present.
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// ExtractBase returns the href of the first <base href="..."> element in the document's
+// <head>, as written in the page, or an empty string if the page declares no base URL.
+// Browsers resolve every relative link of the page against it instead of the page URL.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//
+// Returns:
+//   - string: The declared base URL, possibly relative to the page URL
+func ExtractBase(n *html.Node) string {
+	head := findElement(n, atom.Head)
+	if head == nil {
+		return ""
+	}
+
+	var walk func(*html.Node) (string, bool)
+	walk = func(node *html.Node) (string, bool) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Base {
+			if href := strings.TrimSpace(attrValue(node, "href")); href != "" {
+				return href, true
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if href, ok := walk(child); ok {
+				return href, true
+			}
+		}
+		return "", false
+	}
+
+	href, _ := walk(head)
+	return href
+}
+
+// ExtractLinks traverses an HTML document tree and extracts all internal links (anchor elements,
+// and the <area> elements of image maps). It performs a depth-first traversal of the DOM,
+// identifying anchor and area tags with href attributes that point to internal pages within
+// the same domain. The text of an area link is its alt attribute, since it has no content.
+// Fragments are stripped, since they never reach the server, and duplicate links are
+// automatically filtered out. When the page declares a <base href>, every link is resolved
+// against it first, as a browser would, so that links relative to the current directory
+// ("page.html") are followed too.
 //
 // Parameters:
 //   - n: Root HTML node to start traversal from
@@ -120,35 +409,55 @@ func extractText(n *html.Node) string {
 // Returns:
 //   - []Link: Slice of unique internal links found in the document
 func ExtractLinks(n *html.Node, baseDomain string) []Link {
+	return extractLinks(n, baseDomain, func(href string) bool {
+		return IsInternalLink(href, baseDomain)
+	})
+}
+
+// extractLinks implements ExtractLinks, deciding with internal which hrefs are internal.
+func extractLinks(n *html.Node, baseDomain string, internal func(href string) bool) []Link {
 	var links []Link
 	// Use a map to track seen URLs and prevent duplicates
 	seen := make(map[string]struct{})
 
+	// The base URL may itself be relative to the page
+	base := ExtractBase(n)
+	if base != "" {
+		base = ResolveURL(baseDomain, base)
+	}
+
 	// Define a recursive function to walk the DOM tree
 	var walk func(*html.Node)
 	walk = func(node *html.Node) {
-		// Check if current node is an anchor element
-		if node.Type == html.ElementNode && node.DataAtom == atom.A {
-			// Look for href attribute in the anchor element
+		// Check if current node is an anchor element or a clickable region of an image map
+		if node.Type == html.ElementNode && (node.DataAtom == atom.A || node.DataAtom == atom.Area) {
+			// Look for href attribute in the element
 			for _, attr := range node.Attr {
-				if attr.Key == "href" && isInternalLink(attr.Val, baseDomain) {
-					href := attr.Val
-
-					// Convert relative URLs to absolute URLs
+				if attr.Key != "href" {
+					continue
+				}
+				href := attr.Val
+				if base != "" {
+					href = ResolveURL(base, href)
+				}
+				if internal(href) {
+					// Convert relative URLs to absolute URLs, dropping any #fragment so that
+					// links to sections of a page don't fetch the page again
 					if strings.HasPrefix(href, "/") {
-						href = resolveURL(baseDomain, href)
+						href = ResolveURL(baseDomain, href)
 					}
+					href = withoutFragment(href)
 
 					// Add link only if we haven't seen it before
 					if _, exists := seen[href]; !exists {
 						seen[href] = struct{}{}
 						links = append(links, Link{
 							Href: href,
-							Text: strings.TrimSpace(extractText(node)),
+							Text: linkText(node),
 						})
 					}
-					break // Found href attribute, no need to check other attributes
 				}
+				break // Found href attribute, no need to check other attributes
 			}
 		}
 
@@ -163,99 +472,128 @@ func ExtractLinks(n *html.Node, baseDomain string) []Link {
 	return links
 }
 
-// isInternalLink determines whether a given link URL is internal to the website being crawled.
-// A link is considered internal if it's either a relative path (starts with "/") or
-// an absolute URL that begins with the base domain.
+// linkText returns the description of a link element: the text content of an anchor, or
+// the alt attribute of an image map area, which has no content of its own.
+func linkText(n *html.Node) string {
+	if n.DataAtom == atom.Area {
+		return strings.Join(strings.Fields(attrValue(n, "alt")), " ")
+	}
+	return strings.TrimSpace(ExtractText(n))
+}
+
+// IsInternalLink determines whether a given link URL is internal to the website being crawled.
+// A link is considered internal if it's either a root-relative path (starts with "/") or
+// an absolute URL on the same scheme and host as baseDomain, compared in their canonical
+// form, so HTTP://Example.COM:80/About is internal to http://example.com. A
+// protocol-relative URL ("//host/path") is internal only if its host is the host of
+// baseDomain. Fragments are ignored, so a link to a section of the current page ("#top")
+// is not a link to another page. Other relative paths ("about", "../contact") and
+// malformed URLs are not internal. The scheme must match exactly; crawls only require
+// that with CrawlOptions.StrictScheme, and otherwise accept http and https alike.
 //
 // Parameters:
 //   - link: The URL to check
-//   - baseDomain: The base domain of the website being crawled
+//   - baseDomain: The base domain of the website being crawled, or any URL on it
 //
 // Returns:
 //   - bool: true if the link is internal, false otherwise
-func isInternalLink(link, baseDomain string) bool {
+func IsInternalLink(link, baseDomain string) bool {
+	return internalLink(link, baseDomain, true)
+}
+
+// internalLink implements IsInternalLink. Without strictScheme, http and https URLs on
+// the same host, port included, are internal to each other.
+func internalLink(link, baseDomain string, strictScheme bool) bool {
+	link, _, _ = strings.Cut(link, "#")
+
 	// Relative paths (e.g., "/about", "/contact") are always internal
-	// Absolute URLs starting with the base domain are also internal
-	return strings.HasPrefix(link, "/") || strings.HasPrefix(link, baseDomain)
+	if strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		return true
+	}
+
+	// Absolute and protocol-relative URLs name a site of their own, which must be the
+	// crawled one; a protocol-relative URL uses the scheme of the base domain
+	u, err := url.Parse(link)
+	base, baseErr := url.Parse(baseDomain)
+	if err != nil || baseErr != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme == "" {
+		u.Scheme = base.Scheme
+	}
+	canonicalize(u)
+	canonicalize(base)
+	if u.Host != base.Host {
+		return false
+	}
+	return u.Scheme == base.Scheme || (!strictScheme && isWebScheme(u.Scheme) && isWebScheme(base.Scheme))
 }
 
-// CrawlBFS performs a breadth-first search crawl of a website starting from the provided links.
-// It systematically visits pages level by level, extracting internal links from each page
-// and adding them to the crawl queue. The crawling stops when the maximum depth is reached
-// or when all discoverable internal pages have been visited.
-//
-// The BFS approach ensures that pages closer to the starting point are crawled first,
-// which is ideal for sitemap generation as it prioritizes more important/accessible pages.
+// isWebScheme reports whether scheme, in lowercase, is http or https.
+func isWebScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https"
+}
+
+// BaseDomain infers the base domain of a crawl from its seed URLs: the scheme and host
+// they all share, such as "https://example.com". Seeds on different schemes or hosts
+// cannot be crawled as one site and are rejected.
 //
 // Parameters:
-//   - links: Initial set of links to start crawling from
-//   - maxDepth: Maximum depth to crawl (0 = only initial links, 1 = one level deep, etc.)
-//   - client: HTTP client for making requests
+//   - seeds: Absolute starting URLs of the crawl
 //
 // Returns:
-//   - []Link: All unique internal links discovered during the crawl
-//   - error: Any error that prevented the crawl from starting
-func CrawlBFS(links []Link, maxDepth int, client *http.Client) ([]Link, error) {
-	// Validate input
-	if len(links) == 0 {
-		return nil, fmt.Errorf("no links to traverse")
+//   - string: The common scheme and host, without a trailing slash
+//   - error: No seeds, a seed that is not an absolute URL, or seeds that disagree
+func BaseDomain(seeds []string) (string, error) {
+	origins, err := SeedOrigins(seeds)
+	if err != nil {
+		return "", err
 	}
-
-	// Track visited URLs to avoid infinite loops and duplicate processing
-	visited := make(map[string]struct{})
-
-	// Node represents a link with its depth in the crawl tree
-	type Node struct {
-		link  Link // The link being processed
-		depth int  // How many levels deep this link is from the starting point
+	if len(origins) > 1 {
+		return "", fmt.Errorf("seed URLs must share a scheme and host: %s and %s differ", origins[0], origins[1])
 	}
+	return origins[0], nil
+}
 
-	// Initialize BFS queue with the first link at depth 0
-	visited[links[0].Href] = struct{}{}
-	queue := []Node{{links[0], 0}}
-
-	// Store all discovered links for the final sitemap
-	var result []Link
-
-	// Process queue until empty (BFS main loop)
-	for len(queue) > 0 {
-		// Dequeue the next node to process
-		currentNode := queue[0]
-		queue = queue[1:]
-
-		// Add current link to results
-		result = append(result, currentNode.link)
-
-		// Skip further crawling if we've reached maximum depth
-		if currentNode.depth >= maxDepth {
-			continue
-		}
+// SeedOrigins returns the distinct sites of a crawl's seed URLs: their scheme and host,
+// such as "https://example.com", compared case-insensitively and listed in the order of
+// their first seed. CrawlBFS treats links to any of them as internal.
+//
+// Parameters:
+//   - seeds: Absolute starting URLs of the crawl
+//
+// Returns:
+//   - []string: The scheme and host of every site, without a trailing slash
+//   - error: No seeds, or a seed that is not an absolute URL
+func SeedOrigins(seeds []string) ([]string, error) {
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no seed URLs")
+	}
 
-		// Fetch and parse the current page to find more internal links
-		doc, err := FetchAndParse(currentNode.link.Href, client)
+	var origins []string
+	for _, seed := range seeds {
+		u, err := url.Parse(seed)
 		if err != nil {
-			fmt.Printf("Warning: Failed to fetch %s: %v\n", currentNode.link.Href, err)
-			continue // Skip this page but continue crawling others
+			return nil, fmt.Errorf("invalid seed URL %q: %w", seed, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("seed URL %q must be absolute", seed)
 		}
 
-		// Extract all internal links from the current page
-		neighbors := ExtractLinks(doc, currentNode.link.Href)
-
-		// Add unvisited neighbors to the queue for future processing
-		for _, neighbor := range neighbors {
-			if _, alreadyVisited := visited[neighbor.Href]; !alreadyVisited {
-				visited[neighbor.Href] = struct{}{}
-				queue = append(queue, Node{neighbor, currentNode.depth + 1})
-			}
+		origin := u.Scheme + "://" + u.Host
+		if !slices.ContainsFunc(origins, func(o string) bool { return strings.EqualFold(o, origin) }) {
+			origins = append(origins, origin)
 		}
 	}
-
-	return result, nil
+	return origins, nil
 }
 
-// resolveURL converts a relative URL to an absolute URL using the provided base URL.
+// ResolveURL converts a relative URL to an absolute URL using the provided base URL.
 // This function handles the conversion of relative paths (e.g., "/about", "../contact")
-// to fully qualified URLs that can be used for HTTP requests.
+// to fully qualified URLs that can be used for HTTP requests. Relative paths are resolved
+// against the path of base as a browser would ("about" against "https://example.com/docs/"
+// gives "https://example.com/docs/about"), and protocol-relative URLs ("//host/path")
+// take the scheme of base.
 //
 // Parameters:
 //   - base: The base URL to resolve relative URLs against
@@ -263,7 +601,7 @@ func CrawlBFS(links []Link, maxDepth int, client *http.Client) ([]Link, error) {
 //
 // Returns:
 //   - string: The resolved absolute URL, or the original href if resolution fails
-func resolveURL(base, href string) string {
+func ResolveURL(base, href string) string {
 	// Parse the href to determine if it's already absolute
 	hrefURL, err := url.Parse(href)
 	if err != nil {
@@ -292,7 +630,8 @@ func resolveURL(base, href string) string {
 // and includes the required XML header and namespace declarations.
 //
 // The output is formatted with proper indentation for human readability and can be
-// directly saved as a sitemap.xml file or served to search engines.
+// directly saved as a sitemap.xml file or served to search engines. For large sitemaps
+// prefer EncodeXMLTo, which streams to an io.Writer without building the document as a string.
 //
 // Parameters:
 //   - links: Slice of Link structs containing the URLs to include in the sitemap
@@ -301,25 +640,318 @@ func resolveURL(base, href string) string {
 //   - string: Complete XML sitemap as a string with proper formatting
 //   - error: Any error that occurred during XML marshaling
 func EncodeXML(links []Link) (string, error) {
-	// Convert Link structs to Url structs for XML serialization
-	// We only need the URL location for the sitemap, not the link text
-	urls := make([]Url, 0, len(links))
+	var sb strings.Builder
+	if err := EncodeXMLTo(&sb, links); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// EncodeXMLTo encodes links as an XML sitemap and streams it to w. The <urlset> element
+// is written token by token and every <url> entry is flushed to w as soon as it has been
+// encoded, so the document is never held in memory as a whole. This makes it suitable
+// for writing large sitemaps directly to files, gzip writers or HTTP responses.
+//
+// Parameters:
+//   - w: Destination for the encoded sitemap
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func EncodeXMLTo(w io.Writer, links []Link) error {
+	return WriteXMLIndent(links, w, "  ")
+}
+
+// WriteXML encodes links as an XML sitemap and writes it to w. It is EncodeXMLTo with
+// the arguments in the order used by the other encoders of this package, and its output
+// is byte-for-byte identical to EncodeXML.
+//
+// Parameters:
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//   - w: Destination for the encoded sitemap
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func WriteXML(links []Link, w io.Writer) error {
+	return EncodeXMLTo(w, links)
+}
+
+// WriteXMLIndent encodes links as an XML sitemap like WriteXML, but nests elements using
+// the given indent string. An empty indent produces compact output with the whole
+// <urlset> on a single line after the XML declaration, which noticeably shrinks large
+// sitemaps.
+//
+// Parameters:
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//   - w: Destination for the encoded sitemap
+//   - indent: String repeated once per nesting level, or "" for compact output
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func WriteXMLIndent(links []Link, w io.Writer, indent string) error {
+	return writeXML(links, w, indent, false)
+}
+
+// WriteXMLExtended encodes links like WriteXMLIndent, and additionally records the <title>
+// of every crawled page as an XML comment at the start of its <url> entry. The protocol
+// has no element for titles and search engines ignore comments, so the document remains
+// a valid sitemap that is easier to review by hand.
+//
+// Parameters:
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//   - w: Destination for the encoded sitemap
+//   - indent: String repeated once per nesting level, or "" for compact output
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func WriteXMLExtended(links []Link, w io.Writer, indent string) error {
+	return writeXML(links, w, indent, true)
+}
+
+// writeXML implements WriteXMLIndent and WriteXMLExtended, adding title comments to the
+// entries when titles is set.
+func writeXML(links []Link, w io.Writer, indent string, titles bool) error {
+	// Declare extension namespaces only when they are actually used, so plain sitemaps
+	// stay byte-for-byte unchanged. Entries that will be skipped don't count.
+	root := xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace}},
+	}
+	hasImages, hasVideos, hasAlternates := false, false, false
 	for _, link := range links {
-		urls = append(urls, Url{Loc: link.Href})
+		if _, err := SanitizeLoc(link.Href); err != nil {
+			continue
+		}
+		hasImages = hasImages || len(link.Images) > 0
+		hasVideos = hasVideos || len(link.Videos) > 0
+		hasAlternates = hasAlternates || len(link.Alternates) > 0
+	}
+	if hasImages {
+		root.Attr = append(root.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:image"}, Value: imageNamespace})
+	}
+	if hasVideos {
+		root.Attr = append(root.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:video"}, Value: videoNamespace})
+	}
+	if hasAlternates {
+		root.Attr = append(root.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:xhtml"}, Value: xhtmlNamespace})
+	}
+
+	return encodeURLSet(w, root, indent, titles, slices.Values(links))
+}
+
+// EncodeXMLStream writes an XML sitemap entry by entry as links arrive on a channel, for
+// crawls too large to hold in memory; see StreamTo. The XML declaration and the opening
+// <urlset> are written at once, every link is encoded and flushed as soon as it is
+// received, and </urlset> is written when the channel is closed. Since the entries are
+// not known in advance, the image, video and xhtml namespaces are always declared.
+//
+// After a write error the remaining links are still received and discarded, so the
+// sender never blocks; the error is returned once the channel is closed.
+//
+// Parameters:
+//   - links: The links to write, in order; the caller closes it after the last one
+//   - w: Destination for the encoded sitemap
+//
+// Returns:
+//   - error: Any error that occurred during XML encoding or writing
+func EncodeXMLStream(links <-chan Link, w io.Writer) error {
+	root := xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace},
+			{Name: xml.Name{Local: "xmlns:image"}, Value: imageNamespace},
+			{Name: xml.Name{Local: "xmlns:video"}, Value: videoNamespace},
+			{Name: xml.Name{Local: "xmlns:xhtml"}, Value: xhtmlNamespace},
+		},
+	}
+	received := func(yield func(Link) bool) {
+		for link := range links {
+			if !yield(link) {
+				return
+			}
+		}
+	}
+	err := encodeURLSet(w, root, "  ", false, received)
+
+	// Drain the channel so the sender is not left blocked
+	for range links {
+	}
+	return err
+}
+
+// encodeURLSet writes the XML declaration, the root element and one <url> entry per link,
+// flushing every entry to w as soon as it has been encoded. Title comments are added to
+// the entries when titles is set.
+func encodeURLSet(w io.Writer, root xml.StartElement, indent string, titles bool, links iter.Seq[Link]) error {
+	// Write the standard XML declaration header
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("writing XML header: %w", err)
+	}
+
+	// Stream the document, indented for readability unless compact output was requested
+	enc := xml.NewEncoder(w)
+	enc.Indent("", indent)
+	if err := enc.EncodeToken(root); err != nil {
+		return fmt.Errorf("marshaling XML: %w", err)
+	}
+
+	// Convert and flush one entry at a time so memory use does not grow with the sitemap
+	entry := xml.StartElement{Name: xml.Name{Local: "url"}}
+	for link := range links {
+		// A malformed URL would make the whole file invalid, so it is skipped instead
+		u, err := urlFromLink(link)
+		if err != nil {
+			currentLogger().Warn("skipping sitemap entry", "url", link.Href, "err", err)
+			continue
+		}
+		if titles {
+			u.Comment = titleComment(link.Title)
+		}
+		if err := enc.EncodeElement(u, entry); err != nil {
+			return fmt.Errorf("marshaling XML entry %s: %w", u.Loc, err)
+		}
+		if err := enc.Flush(); err != nil {
+			return fmt.Errorf("writing XML: %w", err)
+		}
+	}
+
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return fmt.Errorf("marshaling XML: %w", err)
+	}
+	return enc.Close()
+}
+
+// ReadXML parses an XML sitemap, such as a previously generated sitemap.xml, and returns
+// its URL entries.
+//
+// Parameters:
+//   - r: Source of the sitemap document
+//
+// Returns:
+//   - []Url: The URL entries of the sitemap in document order
+//   - error: Any error that occurred during XML parsing
+func ReadXML(r io.Reader) ([]Url, error) {
+	var urlset Urlset
+	if err := xml.NewDecoder(r).Decode(&urlset); err != nil {
+		return nil, fmt.Errorf("parsing sitemap XML: %w", err)
+	}
+	return urlset.Urls, nil
+}
+
+// urlFromLink converts a crawled Link into its <url> sitemap entry, sanitizing the
+// location with SanitizeLoc. The link text is not part of the sitemap protocol and is
+// dropped here.
+func urlFromLink(link Link) (Url, error) {
+	loc, err := SanitizeLoc(link.Href)
+	if err != nil {
+		return Url{}, err
+	}
+	return Url{
+		Loc:        loc,
+		LastMod:    link.LastMod,
+		ChangeFreq: link.ChangeFreq,
+		Priority:   link.Priority,
+		Images:     link.Images,
+		Videos:     link.Videos,
+		Alternates: link.Alternates,
+	}, nil
+}
+
+// titleComment turns a page title into the text of an XML comment. Runs of dashes are
+// broken up and the text is padded with spaces, since a comment may neither contain "--"
+// nor end with "-". An empty title yields no comment.
+func titleComment(title string) string {
+	if title == "" {
+		return ""
+	}
+	for strings.Contains(title, "--") {
+		title = strings.ReplaceAll(title, "--", "- -")
+	}
+	return " " + title + " "
+}
+
+// ChunkLinks partitions links into consecutive groups of at most size elements.
+// It is used to spread large crawls over several sitemap files so that each file
+// stays within MaxURLsPerSitemap. The returned slices share the backing array of links.
+//
+// Parameters:
+//   - links: The links to partition
+//   - size: Maximum number of links per chunk (must be positive)
+//
+// Returns:
+//   - [][]Link: The chunks in their original order
+func ChunkLinks(links []Link, size int) [][]Link {
+	if size <= 0 {
+		return [][]Link{links}
+	}
+
+	var chunks [][]Link
+	for start := 0; start < len(links); start += size {
+		end := min(start+size, len(links))
+		chunks = append(chunks, links[start:end])
+	}
+	return chunks
+}
+
+// SplitAndEncode partitions links into chunks of at most maxPerFile entries and
+// MaxSitemapBytes bytes, and encodes each chunk as a standalone sitemap document. The
+// caller is expected to write every returned document to its own file and reference those
+// files from a sitemap index produced by EncodeSitemapIndex.
+//
+// Parameters:
+//   - links: All links to include across the generated sitemaps
+//   - maxPerFile: Maximum URLs per sitemap, between 1 and MaxURLsPerSitemap
+//
+// Returns:
+//   - []string: One complete XML sitemap per chunk, in link order
+//   - error: An invalid maxPerFile, an entry too large for any file, or any error that
+//     occurred during XML marshaling
+func SplitAndEncode(links []Link, maxPerFile int) ([]string, error) {
+	if maxPerFile <= 0 || maxPerFile > MaxURLsPerSitemap {
+		return nil, fmt.Errorf("max URLs per file must be between 1 and %d, got %d", MaxURLsPerSitemap, maxPerFile)
+	}
+
+	chunks, err := ChunkBySize(links, maxPerFile, MaxSitemapBytes)
+	if err != nil {
+		return nil, err
+	}
+	sitemaps := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		sitemapXML, err := EncodeXML(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("encoding sitemap %d: %w", i+1, err)
+		}
+		sitemaps = append(sitemaps, sitemapXML)
+	}
+
+	return sitemaps, nil
+}
+
+// EncodeSitemapIndex generates a sitemap index document referencing the given sitemap files.
+// The locations must be absolute URLs, as required by the protocol, since search engines
+// fetch each child sitemap independently of the index.
+//
+// Parameters:
+//   - sitemaps: Absolute URLs of the child sitemap files
+//
+// Returns:
+//   - string: Complete sitemap index XML with declaration header
+//   - error: Any error that occurred during XML marshaling
+func EncodeSitemapIndex(sitemaps []string) (string, error) {
+	entries := make([]SitemapEntry, 0, len(sitemaps))
+	for _, loc := range sitemaps {
+		entries = append(entries, SitemapEntry{Loc: loc})
 	}
 
-	// Create the root urlset element with proper namespace
-	urlset := Urlset{
-		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", // Required sitemap namespace
-		Urls:  urls,
+	index := Sitemapindex{
+		Xmlns:    sitemapNamespace,
+		Sitemaps: entries,
 	}
 
-	// Marshal the structure to XML with proper indentation for readability
-	output, err := xml.MarshalIndent(urlset, "", "  ")
+	output, err := xml.MarshalIndent(index, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("marshaling XML: %w", err)
+		return "", fmt.Errorf("marshaling sitemap index: %w", err)
 	}
 
-	// Prepend the standard XML declaration header
 	return xml.Header + string(output), nil
 }
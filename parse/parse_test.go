@@ -0,0 +1,363 @@
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// parseHTML parses a fixture page, failing the test if it cannot.
+func parseHTML(t *testing.T, page string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parsing fixture page: %v", err)
+	}
+	return doc
+}
+
+// linkHrefs returns the hrefs of links, in order.
+func linkHrefs(links []Link) []string {
+	hrefs := make([]string, len(links))
+	for i, link := range links {
+		hrefs[i] = link.Href
+	}
+	return hrefs
+}
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		base, href, want string
+	}{
+		{"https://example.com", "/about", "https://example.com/about"},
+		{"https://example.com/docs/", "install", "https://example.com/docs/install"},
+		{"https://example.com/docs/intro", "install", "https://example.com/docs/install"},
+		{"https://example.com/docs/intro", "../blog", "https://example.com/blog"},
+		{"https://example.com/docs/", "?page=2", "https://example.com/docs/?page=2"},
+		{"https://example.com", "https://other.org/x", "https://other.org/x"},
+	}
+	for _, tt := range tests {
+		if got := ResolveURL(tt.base, tt.href); got != tt.want {
+			t.Errorf("ResolveURL(%q, %q) = %q, want %q", tt.base, tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "root-relative and absolute internal links",
+			page: `<a href="/about">About</a> <a href="https://example.com/blog">Blog</a>`,
+			want: []string{"https://example.com/about", "https://example.com/blog"},
+		},
+		{
+			name: "external links and other schemes are filtered out",
+			page: `<a href="https://other.org/">Other</a> <a href="mailto:team@example.com">Mail</a>
+				<a href="javascript:void(0)">JS</a> <a href="/contact">Contact</a>`,
+			want: []string{"https://example.com/contact"},
+		},
+		{
+			name: "subdomains are external",
+			page: `<a href="https://blog.example.com/">Blog</a> <a href="/">Home</a>`,
+			want: []string{"https://example.com/"},
+		},
+		{
+			name: "duplicates are listed once",
+			page: `<a href="/about">About</a> <nav><a href="/about">About us</a></nav>`,
+			want: []string{"https://example.com/about"},
+		},
+		{
+			name: "anchors without href are ignored",
+			page: `<a name="top">Top</a> <a href="/faq">FAQ</a>`,
+			want: []string{"https://example.com/faq"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := linkHrefs(ExtractLinks(parseHTML(t, tt.page), "https://example.com"))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractLinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinksText(t *testing.T) {
+	links := ExtractLinks(parseHTML(t, `<a href="/about">  About <b>us</b> </a>`), "https://example.com")
+	if len(links) != 1 || links[0].Text != "About us" {
+		t.Errorf("ExtractLinks = %+v, want one link with text %q", links, "About us")
+	}
+}
+
+func TestEncodeXMLSanitizesLocs(t *testing.T) {
+	out, err := EncodeXML([]Link{
+		{Href: "https://example.com/search?q=a&page=2"},
+		{Href: "https://example.com/my page"},
+		{Href: "https://example.com/café"},
+		{Href: "https://example.com/%zz"},
+	})
+	if err != nil {
+		t.Fatalf("EncodeXML: %v", err)
+	}
+
+	for _, want := range []string{
+		"<loc>https://example.com/search?q=a&amp;page=2</loc>",
+		"<loc>https://example.com/my%20page</loc>",
+		"<loc>https://example.com/caf%C3%A9</loc>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "<loc>"); got != 3 {
+		t.Errorf("%d entries, want the malformed URL skipped:\n%s", got, out)
+	}
+}
+
+// tocPage is a page at /docs/install whose table of contents links to its own sections.
+const tocPage = `<nav>
+	<a href="#linux">Linux</a> <a href="#mac">macOS</a> <a href="#windows">Windows</a>
+	<a href="/docs/install#linux">Linux</a> <a href="/docs/install#mac">macOS</a>
+	<a href="https://example.com/docs/install#windows">Windows</a> <a href="#">Top</a>
+</nav>
+<h2 id="linux">Linux</h2> <h2 id="mac">macOS</h2> <h2 id="windows">Windows</h2>`
+
+func TestExtractLinksTableOfContents(t *testing.T) {
+	got := linkHrefs(ExtractLinks(parseHTML(t, tocPage), "https://example.com"))
+	want := []string{"https://example.com/docs/install"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExtractLinks = %v, want %v", got, want)
+	}
+}
+
+func TestWriteXMLCompactMatchesIndented(t *testing.T) {
+	half := 0.5
+	links := []Link{
+		{Href: "https://example.com/", LastMod: "2024-01-02", ChangeFreq: "daily", Priority: &half},
+		{Href: "https://example.com/search?q=a&page=2"},
+		{Href: "https://example.com/gallery", Images: []Image{{Loc: "https://example.com/cat.jpg"}}},
+	}
+
+	var indented, compact strings.Builder
+	if err := WriteXMLIndent(links, &indented, "  "); err != nil {
+		t.Fatalf("WriteXMLIndent: %v", err)
+	}
+	if err := WriteXMLIndent(links, &compact, ""); err != nil {
+		t.Fatalf("WriteXMLIndent without indent: %v", err)
+	}
+	if !strings.HasPrefix(compact.String(), xml.Header) {
+		t.Errorf("compact output lacks the XML declaration:\n%s", compact.String())
+	}
+	if lines := strings.Count(strings.TrimSpace(compact.String()), "\n"); lines != 1 {
+		t.Errorf("compact output spans %d lines, want the declaration and one line", lines+1)
+	}
+
+	var fromIndented, fromCompact Urlset
+	if err := xml.Unmarshal([]byte(indented.String()), &fromIndented); err != nil {
+		t.Fatalf("parsing indented output: %v", err)
+	}
+	if err := xml.Unmarshal([]byte(compact.String()), &fromCompact); err != nil {
+		t.Fatalf("parsing compact output: %v", err)
+	}
+	if !reflect.DeepEqual(fromIndented, fromCompact) {
+		t.Errorf("compact output parses to %+v, indented output to %+v", fromCompact, fromIndented)
+	}
+	if len(fromCompact.Urls) != len(links) {
+		t.Errorf("parsed %d entries, want %d", len(fromCompact.Urls), len(links))
+	}
+}
+
+func TestSectionLinksDoNotInflateCount(t *testing.T) {
+	page := `<a href="#section">Section</a> <a href="#section">Again</a> <a href="#other">Other</a>
+		<a href="/docs#section1">Docs 1</a> <a href="/docs#section2">Docs 2</a> <a href="/docs">Docs</a>`
+	links := ExtractLinks(parseHTML(t, page), "https://example.com")
+	want := []string{"https://example.com/docs"}
+	if got := linkHrefs(links); !slices.Equal(got, want) {
+		t.Errorf("ExtractLinks = %v, want %v", got, want)
+	}
+
+	for _, tt := range []struct {
+		link string
+		want bool
+	}{
+		{"#section", false},
+		{"/docs#section1", true},
+		{"https://example.com/docs#section2", true},
+		{"https://other.org/docs#section", false},
+	} {
+		if got := IsInternalLink(tt.link, "https://example.com"); got != tt.want {
+			t.Errorf("IsInternalLink(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestResolveURLEdgeCases(t *testing.T) {
+	tests := []struct {
+		name, base, href, want string
+	}{
+		{"protocol-relative", "https://example.com/docs/", "//cdn.example.com/app.js", "https://cdn.example.com/app.js"},
+		{"protocol-relative keeps http", "http://example.com/", "//example.com/about", "http://example.com/about"},
+		{"base with a file path", "https://example.com/docs/intro.html", "setup.html", "https://example.com/docs/setup.html"},
+		{"base with a path, root-relative href", "https://example.com/docs/intro", "/blog", "https://example.com/blog"},
+		{"dot segments", "https://example.com/a/b/c", "./../d", "https://example.com/a/d"},
+		{"empty href is the base", "https://example.com/docs/intro", "", "https://example.com/docs/intro"},
+		{"malformed href", "https://example.com/", "http://[::1", "http://[::1"},
+		{"malformed escape", "https://example.com/", "/a%zz", "/a%zz"},
+		{"malformed base", "://bad", "/about", "/about"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveURL(tt.base, tt.href); got != tt.want {
+				t.Errorf("ResolveURL(%q, %q) = %q, want %q", tt.base, tt.href, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInternalLink(t *testing.T) {
+	tests := []struct {
+		name, link, base string
+		want             bool
+	}{
+		{"root-relative", "/about", "https://example.com", true},
+		{"same host", "https://example.com/about", "https://example.com", true},
+		{"other host", "https://other.org/about", "https://example.com", false},
+		{"subdomain", "https://www.example.com/", "https://example.com", false},
+		{"protocol-relative same host", "//example.com/about", "https://example.com", true},
+		{"protocol-relative other host", "//cdn.example.org/app.js", "https://example.com", false},
+		{"double slash is not root-relative", "//about", "https://example.com", false},
+		{"base with a path", "https://example.com/blog", "https://example.com/docs/intro", true},
+		{"base with a path, root-relative", "/blog", "https://example.com/docs/intro", true},
+		{"mixed case and default port", "HTTP://Example.COM:80/About", "http://example.com", true},
+		{"other port", "https://example.com:8443/", "https://example.com", false},
+		{"other scheme", "http://example.com/about", "https://example.com", false},
+		{"relative path", "about", "https://example.com", false},
+		{"parent path", "../contact", "https://example.com/docs/", false},
+		{"mailto", "mailto:team@example.com", "https://example.com", false},
+		{"malformed link", "http://[::1", "https://example.com", false},
+		{"malformed base", "https://example.com/about", "://bad", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInternalLink(tt.link, tt.base); got != tt.want {
+				t.Errorf("IsInternalLink(%q, %q) = %v, want %v", tt.link, tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractBase(t *testing.T) {
+	tests := []struct {
+		name, page, want string
+	}{
+		{"no base", `<head><title>T</title></head>`, ""},
+		{"relative base", `<head><base href="/subdir/"></head>`, "/subdir/"},
+		{"first base wins", `<head><base target="_blank"><base href=" /a/ "><base href="/b/"></head>`, "/a/"},
+		{"base outside head ignored", `<body><base href="/subdir/"></body>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractBase(parseHTML(t, tt.page)); got != tt.want {
+				t.Errorf("ExtractBase = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinksResolvesAgainstBase(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "base in another directory",
+			page: `<head><base href="/archive/2020/"></head><a href="page.html">Page</a> <a href="../2021/">2021</a>`,
+			want: []string{"https://example.com/archive/2020/page.html", "https://example.com/archive/2021/"},
+		},
+		{
+			name: "root-relative links ignore the base path",
+			page: `<head><base href="/archive/2020/"></head><a href="/about">About</a>`,
+			want: []string{"https://example.com/about"},
+		},
+		{
+			name: "absolute base on the same host",
+			page: `<head><base href="https://example.com/docs/v2/"></head><a href="install">Install</a>`,
+			want: []string{"https://example.com/docs/v2/install"},
+		},
+		{
+			name: "base on another host makes relative links external",
+			page: `<head><base href="https://cdn.example.org/"></head><a href="page.html">Page</a> <a href="/about">About</a>`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := linkHrefs(ExtractLinks(parseHTML(t, tt.page), "https://example.com"))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractLinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinksImageMaps(t *testing.T) {
+	page := `<img src="/map.png" usemap="#regions">
+	<map name="regions">
+		<area shape="rect" coords="0,0,10,10" href="/north" alt="North  region">
+		<area shape="circle" coords="20,20,5" href="https://example.com/south#top" alt="South">
+		<area shape="rect" coords="0,0,1,1" href="/north" alt="North again">
+		<area shape="rect" coords="5,5,6,6" href="https://other.org/east" alt="East">
+		<area shape="default" nohref alt="Nowhere">
+	</map>
+	<a href="/west"><img src="/west.png" alt="ignored">West</a>`
+
+	links := ExtractLinks(parseHTML(t, page), "https://example.com")
+	want := []Link{
+		{Href: "https://example.com/north", Text: "North region"},
+		{Href: "https://example.com/south", Text: "South"},
+		{Href: "https://example.com/west", Text: "West"},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("ExtractLinks = %+v, want %+v", links, want)
+	}
+}
+
+// manyLinks returns n links with distinct URLs, as a large crawl would.
+func manyLinks(n int) []Link {
+	links := make([]Link, n)
+	for i := range links {
+		links[i] = Link{Href: fmt.Sprintf("https://example.com/products/%d?ref=sitemap&page=%d", i, i%50), LastMod: "2024-01-02"}
+	}
+	return links
+}
+
+func BenchmarkEncodeXML(b *testing.B) {
+	links := manyLinks(100_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := EncodeXML(links); err != nil {
+			b.Fatalf("EncodeXML: %v", err)
+		}
+	}
+}
+
+func BenchmarkEncodeXMLTo(b *testing.B) {
+	links := manyLinks(100_000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := EncodeXMLTo(io.Discard, links); err != nil {
+			b.Fatalf("EncodeXMLTo: %v", err)
+		}
+	}
+}
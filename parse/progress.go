@@ -0,0 +1,62 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progress writes a line to a writer before every fetch of a crawl, so long crawls show
+// signs of life, and passes the same events on to the crawl's Metrics. A progress with a
+// nil writer stays silent. It is safe for concurrent use by the crawl workers.
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	metrics Metrics // Receives every event too, if non-nil
+	queued  int     // Pages waiting to be fetched
+	done    int     // Pages fetched so far, successfully or not
+}
+
+// enqueue records n more pages waiting to be fetched.
+func (p *progress) enqueue(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued += n
+	if p.metrics != nil {
+		p.metrics.Queued(n)
+	}
+}
+
+// dropped records a queued page that is given up without being fetched.
+func (p *progress) dropped() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued--
+	if p.metrics != nil {
+		p.metrics.Queued(-1)
+	}
+}
+
+// visiting reports that the fetch of rawURL at the given depth is starting.
+func (p *progress) visiting(depth int, rawURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued--
+	if p.metrics != nil {
+		p.metrics.Visiting(depth)
+	}
+	if p.w != nil {
+		fmt.Fprintf(p.w, "[depth %d] visiting %s (%d queued, %d done)\n", depth, rawURL, p.queued, p.done)
+	}
+}
+
+// finished records the completion of a fetch that took d and failed if err is non-nil.
+func (p *progress) finished(d time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if p.metrics != nil {
+		p.metrics.Fetched(d, err != nil)
+	}
+}
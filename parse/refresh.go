@@ -0,0 +1,70 @@
+package parse
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ExtractMetaRefresh returns the target of the document's first
+// <meta http-equiv="refresh"> tag, as in content="0; url=/newpage", which older pages use
+// instead of an HTTP redirect. The target is returned as written, possibly relative to the
+// page URL. A refresh without a target only reloads the page and is not reported.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//
+// Returns:
+//   - string: The URL the page redirects to
+//   - bool: true if the page declares a meta refresh with a target
+func ExtractMetaRefresh(n *html.Node) (string, bool) {
+	var walk func(*html.Node) (string, bool, bool)
+	walk = func(node *html.Node) (target string, ok, found bool) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Meta &&
+			strings.EqualFold(strings.TrimSpace(attrValue(node, "http-equiv")), "refresh") {
+			target, ok = parseRefreshContent(attrValue(node, "content"))
+			return target, ok, true
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if target, ok, found := walk(child); found {
+				return target, ok, true
+			}
+		}
+		return "", false, false
+	}
+
+	// Browsers only honor the first refresh tag, even when it has no target
+	target, ok, _ := walk(n)
+	return target, ok
+}
+
+// parseRefreshContent extracts the URL from the content of a meta refresh tag, following
+// the lenient parsing of browsers: a delay, a ";" or "," separator, an optional "url="
+// prefix in any case and an optionally quoted URL, as in "5;URL='/next'".
+func parseRefreshContent(content string) (string, bool) {
+	// Skip the delay and the separator after it
+	rest := strings.TrimLeft(strings.TrimSpace(content), "0123456789.")
+	rest = strings.TrimSpace(rest)
+	if rest != "" && (rest[0] == ';' || rest[0] == ',') {
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	// The "url=" prefix is optional
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		if value := strings.TrimSpace(rest[3:]); strings.HasPrefix(value, "=") {
+			rest = strings.TrimSpace(value[1:])
+		}
+	}
+
+	// A quoted URL ends at its closing quote, an unquoted one at the end of the content
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		if end := strings.IndexByte(rest[1:], rest[0]); end >= 0 {
+			rest = rest[1 : end+1]
+		} else {
+			rest = rest[1:]
+		}
+	}
+	rest = strings.TrimSpace(rest)
+	return rest, rest != ""
+}
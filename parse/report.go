@@ -0,0 +1,223 @@
+package parse
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"time"
+)
+
+// Report summarizes a crawl for people rather than search engines. Pass a Report in
+// CrawlOptions.Report to have CrawlBFS fill it in, then render it with WriteHTML.
+// The zero value is ready to use. A Report is not safe for concurrent use; CrawlBFS only
+// updates it from its coordinating goroutine.
+type Report struct {
+	Total    int            // Number of URLs listed in the sitemap
+	ByDepth  []DepthCount   // Listed URLs per crawl depth, in increasing depth
+	Failures []FetchFailure // Pages that could not be fetched, in crawl order
+	Timings  []PageTiming   // Fetch duration of every fetched page, in crawl order
+
+	// InsecureFallbacks lists the pages CrawlOptions.PreferHTTPS had to fetch over http
+	// because their https form could not be reached, in crawl order.
+	InsecureFallbacks []string
+
+	// NonHTML lists the fetched pages that are not HTML documents, such as PDFs and
+	// images, which were not parsed for links, in crawl order.
+	NonHTML []NonHTMLPage
+
+	// SkippedLongURLs is the number of URLs left out for exceeding MaxLocLength.
+	// It is set by the caller, since the length policy is applied after crawling.
+	SkippedLongURLs int
+}
+
+// DepthCount is the number of listed URLs found at one crawl depth.
+type DepthCount struct {
+	Depth int
+	Count int
+}
+
+// FetchFailure describes a page that could not be fetched.
+type FetchFailure struct {
+	URL      string // The page that failed
+	Status   int    // HTTP status code, 0 if no response was received
+	Error    string // Description of the failure
+	Referrer string // Page the URL was discovered on, empty for seeds
+	Text     string // Anchor text of the link that led to the page
+}
+
+// PageTiming records how long fetching a page took, including any retries.
+type PageTiming struct {
+	URL      string
+	Duration time.Duration
+}
+
+// addListed counts a URL included in the sitemap at the given depth.
+func (r *Report) addListed(depth int) {
+	r.Total++
+	r.ByDepth = addDepthCount(r.ByDepth, depth)
+}
+
+// CountByDepth counts links per crawl depth, for example to see how deep a site's
+// content lies.
+//
+// Parameters:
+//   - links: Crawled links with their Depth set
+//
+// Returns:
+//   - []DepthCount: The number of links at every depth that has any, in increasing depth
+func CountByDepth(links []Link) []DepthCount {
+	var counts []DepthCount
+	for _, link := range links {
+		counts = addDepthCount(counts, link.Depth)
+	}
+	return counts
+}
+
+// addDepthCount increments the count of depth in counts, which is kept sorted by depth.
+func addDepthCount(counts []DepthCount, depth int) []DepthCount {
+	i, found := slices.BinarySearchFunc(counts, depth, func(dc DepthCount, d int) int {
+		return cmp.Compare(dc.Depth, d)
+	})
+	if !found {
+		counts = slices.Insert(counts, i, DepthCount{Depth: depth})
+	}
+	counts[i].Count++
+	return counts
+}
+
+// addPage records the fetch outcome of a crawled page. Pages that were not fetched
+// because the depth limit was reached are ignored, and pages given up before their
+// fetch started are only listed as failures.
+func (r *Report) addPage(page pageResult) {
+	if page.skipped {
+		return
+	}
+	if !page.abandoned {
+		r.Timings = append(r.Timings, PageTiming{URL: page.link.Href, Duration: page.duration})
+	}
+	if page.insecure {
+		r.InsecureFallbacks = append(r.InsecureFallbacks, page.link.Href)
+	}
+	if page.nonHTML {
+		r.NonHTML = append(r.NonHTML, NonHTMLPage{URL: page.link.Href, ContentType: page.link.ContentType, Size: page.size})
+	}
+	if page.fetchErr != nil {
+		r.Failures = append(r.Failures, FetchFailure{
+			URL:      page.link.Href,
+			Status:   page.link.StatusCode,
+			Error:    page.fetchErr.Error(),
+			Referrer: page.link.Parent,
+			Text:     page.link.Text,
+		})
+	}
+}
+
+// Slowest returns up to n fetched pages ordered from slowest to fastest.
+//
+// Parameters:
+//   - n: Maximum number of pages to return
+//
+// Returns:
+//   - []PageTiming: The slowest pages; ties keep their crawl order
+func (r *Report) Slowest(n int) []PageTiming {
+	timings := slices.Clone(r.Timings)
+	slices.SortStableFunc(timings, func(a, b PageTiming) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return timings[:min(max(n, 0), len(timings))]
+}
+
+// reportTemplate renders a Report as a standalone HTML page. html/template escapes every
+// URL and anchor text according to its context, so crawled content cannot inject markup.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crawl report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h1>Crawl report</h1>
+<p>{{.Report.Total}} URLs listed in the sitemap.
+{{- if .Report.SkippedLongURLs}} {{.Report.SkippedLongURLs}} URLs were left out for exceeding 2048 characters.{{end}}</p>
+
+<h2>URLs by depth</h2>
+<table>
+<tr><th>Depth</th><th>URLs</th></tr>
+{{- range .Report.ByDepth}}
+<tr><td>{{.Depth}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Failed fetches</h2>
+{{- if .Report.Failures}}
+<table>
+<tr><th>URL</th><th>Status</th><th>Error</th><th>Linked from</th></tr>
+{{- range .Report.Failures}}
+<tr><td>{{.URL}}</td><td>{{if .Status}}{{.Status}}{{else}}–{{end}}</td><td>{{.Error}}</td><td>{{if .Referrer}}{{.Referrer}}{{if .Text}} ("{{.Text}}"){{end}}{{else}}start URL{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Every page was fetched successfully.</p>
+{{- end}}
+
+{{- if .Report.InsecureFallbacks}}
+
+<h2>Fetched over http</h2>
+<p>These pages could not be reached over https and are listed with their http URL.</p>
+<ul>
+{{- range .Report.InsecureFallbacks}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+
+{{- if .Report.NonHTML}}
+
+<h2>Files other than HTML</h2>
+<p>These URLs were fetched but not parsed for links.</p>
+<table>
+<tr><th>URL</th><th>Type</th><th>Size</th></tr>
+{{- range .Report.NonHTML}}
+<tr><td>{{.URL}}</td><td>{{.ContentType}}</td><td>{{if ge .Size 0}}{{.Size}} bytes{{else}}–{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Slowest pages</h2>
+<table>
+<tr><th>URL</th><th>Fetch time</th></tr>
+{{- range .Slowest}}
+<tr><td>{{.URL}}</td><td>{{.Duration}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page.
+//
+// Parameters:
+//   - w: Destination for the HTML document
+//   - slowest: Number of slowest pages to list
+//
+// Returns:
+//   - error: Any error that occurred while rendering or writing
+func (r *Report) WriteHTML(w io.Writer, slowest int) error {
+	data := struct {
+		Report  *Report
+		Slowest []PageTiming
+	}{r, r.Slowest(slowest)}
+
+	if err := reportTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("rendering report: %w", err)
+	}
+	return nil
+}
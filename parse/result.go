@@ -0,0 +1,176 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// CrawlResult is the outcome of a CrawlBFS call: the links to list in the sitemap
+// together with statistics about how the crawl went.
+type CrawlResult struct {
+	Links           []Link        // All unique internal links to list, in crawl order
+	Errors          []CrawlError  // Pages that could not be fetched or were abandoned, in crawl order
+	PagesVisited    int           // Pages fetched, successfully or not
+	PagesFailed     int           // Fetched pages that returned an error
+	PagesSkipped    int           // Pages listed without being fetched because of the depth limit
+	PagesAbandoned  int           // Pages given up before their fetch started, because ctx ended or rate limiting failed
+	PagesNoindex    int           // Fetched pages left out because they are marked noindex
+	PagesUnchanged  int           // Fetched pages that answered 304 Not Modified, see CrawlOptions.Cache
+	NonHTML         []NonHTMLPage // Fetched pages that are not HTML and were not parsed, in crawl order
+	Duration        time.Duration // Wall-clock time of the whole crawl
+	MaxDepthReached int           // Deepest level that contained at least one page
+	Truncated       bool          // The crawl stopped early because CrawlOptions.MaxPages was reached
+	Resumed         bool          // The crawl continued from CrawlOptions.CheckpointFile
+	Interrupted     bool          // The context ended before the crawl was complete; see ErrInterrupted
+	Err             error         // The context error that interrupted the crawl, nil if it was not interrupted
+
+	// SkippedLongURLs is the number of URLs left out for exceeding MaxLocLength. It is set
+	// by the caller, since the length policy is applied after crawling.
+	SkippedLongURLs int
+}
+
+// summaryFailingURLs is the number of failed pages listed by WriteSummary.
+const summaryFailingURLs = 10
+
+// ErrInterrupted is wrapped by the error CrawlBFS and CrawlDFS return when their context
+// ends before the crawl is complete. The partial result is returned alongside it, with
+// CrawlResult.Interrupted and CrawlResult.Err set, so callers can still write the pages
+// found so far; errors.Is with context.Canceled or context.DeadlineExceeded tells an
+// interruption from a timeout.
+var ErrInterrupted = errors.New("crawl interrupted")
+
+// CrawlError describes a page that could not be fetched during a crawl.
+type CrawlError struct {
+	URL        string // The page that failed
+	Depth      int    // Crawl depth of the page
+	StatusCode int    // HTTP status code, 0 if no response was received
+	Err        error  // Why the page could not be fetched
+}
+
+// Error implements the error interface.
+func (e CrawlError) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the underlying fetch error.
+func (e CrawlError) Unwrap() error {
+	return e.Err
+}
+
+// addPage records the fetch outcome of a crawled page.
+func (r *CrawlResult) addPage(page pageResult) {
+	r.MaxDepthReached = max(r.MaxDepthReached, page.link.Depth)
+	if page.skipped {
+		r.PagesSkipped++
+		return
+	}
+	if page.abandoned {
+		r.PagesAbandoned++
+		r.addError(page)
+		return
+	}
+	r.PagesVisited++
+	if page.noindex {
+		r.PagesNoindex++
+	}
+	if page.unchanged {
+		r.PagesUnchanged++
+	}
+	if page.nonHTML {
+		r.NonHTML = append(r.NonHTML, NonHTMLPage{URL: page.link.Href, ContentType: page.link.ContentType, Size: page.size})
+	}
+	if page.fetchErr != nil {
+		r.PagesFailed++
+		r.addError(page)
+	}
+}
+
+// addError records why a crawled page could not be fetched.
+func (r *CrawlResult) addError(page pageResult) {
+	r.Errors = append(r.Errors, CrawlError{
+		URL:        page.link.Href,
+		Depth:      page.link.Depth,
+		StatusCode: page.link.StatusCode,
+		Err:        page.fetchErr,
+	})
+}
+
+// WriteSummary writes a short human-readable summary of the crawl to w. Failed pages are
+// counted per status code and the first ten of them are listed with their error.
+//
+// Parameters:
+//   - w: Destination for the summary, typically os.Stderr
+//
+// Returns:
+//   - error: Any error that occurred while writing
+func (r *CrawlResult) WriteSummary(w io.Writer) error {
+	if r.Truncated {
+		if _, err := fmt.Fprintf(w, "Crawl truncated at %d pages\n", r.PagesVisited); err != nil {
+			return err
+		}
+	}
+	if r.Interrupted {
+		if _, err := fmt.Fprintf(w, "Crawl interrupted after %d pages\n", r.PagesVisited); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, `Crawl statistics:
+  URLs listed:       %d
+  Pages visited:     %d
+  Pages failed:      %d
+  Skipped by depth:  %d
+  Excluded noindex:  %d
+  Max depth reached: %d
+  Duration:          %s
+`, len(r.Links), r.PagesVisited, r.PagesFailed, r.PagesSkipped, r.PagesNoindex, r.MaxDepthReached, r.Duration.Round(time.Millisecond))
+	if err == nil && r.PagesAbandoned > 0 {
+		_, err = fmt.Fprintf(w, "  Not fetched:       %d\n", r.PagesAbandoned)
+	}
+	if err == nil && r.PagesUnchanged > 0 {
+		_, err = fmt.Fprintf(w, "  Not modified:      %d\n", r.PagesUnchanged)
+	}
+	if err == nil && r.SkippedLongURLs > 0 {
+		_, err = fmt.Fprintf(w, "  Skipped too long:  %d\n", r.SkippedLongURLs)
+	}
+	if err == nil && len(r.NonHTML) > 0 {
+		_, err = fmt.Fprintf(w, "  Not HTML:          %d\n", len(r.NonHTML))
+	}
+	if err != nil || len(r.Errors) == 0 {
+		return err
+	}
+
+	// Group the failures by status code, network errors first, then show the first few
+	// in crawl order, which puts the shallowest and most visible pages at the top
+	counts := make(map[int]int)
+	for _, e := range r.Errors {
+		counts[e.StatusCode]++
+	}
+	if _, err := fmt.Fprintln(w, "Errors by status:"); err != nil {
+		return err
+	}
+	for _, code := range slices.Sorted(maps.Keys(counts)) {
+		label := "no response"
+		if code != 0 {
+			label = strconv.Itoa(code)
+		}
+		if _, err := fmt.Fprintf(w, "  %-18s %d\n", label+":", counts[code]); err != nil {
+			return err
+		}
+	}
+
+	shown := r.Errors[:min(len(r.Errors), summaryFailingURLs)]
+	if _, err := fmt.Fprintf(w, "Failing URLs (%d of %d):\n", len(shown), len(r.Errors)); err != nil {
+		return err
+	}
+	for _, e := range shown {
+		if _, err := fmt.Fprintf(w, "  %s\n", e.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxRetryWait caps both the exponential backoff and any Retry-After value sent by
+// the server, so a single misbehaving page cannot stall a worker indefinitely.
+const maxRetryWait = time.Minute
+
+// defaultRetryDelay is the base backoff used by the crawler when CrawlOptions.RetryDelay is unset.
+const defaultRetryDelay = 500 * time.Millisecond
+
+// retryableStatus lists the HTTP status codes that indicate a transient failure.
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// FetchAndParseWithRetry behaves like FetchAndParse but retries transient failures:
+// network errors and the status codes 429, 500, 502, 503 and 504. Retries use truncated
+// exponential backoff with jitter starting at baseDelay, and a Retry-After header sent
+// with a 429 response takes precedence over the computed backoff.
+//
+// Parameters:
+//   - url: The URL to fetch and parse
+//   - client: HTTP client with configured timeout and other settings
+//   - maxRetries: Number of additional attempts after the first failure (0 disables retries)
+//   - baseDelay: Backoff before the first retry; doubled for each subsequent retry
+//
+// Returns:
+//   - *html.Node: Root node of the parsed HTML document
+//   - error: The error of the last attempt if all attempts failed
+func FetchAndParseWithRetry(url string, client *http.Client, maxRetries int, baseDelay time.Duration) (*html.Node, error) {
+	page, err := fetchPageWithRetry(context.Background(), url, client, CrawlOptions{MaxRetries: maxRetries, RetryDelay: baseDelay})
+	return page.doc, err
+}
+
+// fetchPageWithRetry is the fetchPage counterpart of FetchAndParseWithRetry, taking the
+// retry count and base backoff from opts.MaxRetries and opts.RetryDelay.
+func fetchPageWithRetry(ctx context.Context, url string, client *http.Client, opts CrawlOptions) (fetchedPage, error) {
+	return withRetry(ctx, opts, func() (fetchedPage, error) {
+		return fetchPage(ctx, url, client, opts)
+	})
+}
+
+// withRetry calls fetch until it succeeds, fails permanently, or opts.MaxRetries
+// retries have been used, sleeping between attempts as decided by retryWait.
+// Cancelling ctx ends the wait early and returns the last failure.
+func withRetry(ctx context.Context, opts CrawlOptions, fetch func() (fetchedPage, error)) (fetchedPage, error) {
+	for attempt := 0; ; attempt++ {
+		page, err := fetch()
+		if err == nil || attempt >= opts.MaxRetries || !isRetryable(page, err) || ctx.Err() != nil {
+			return page, err
+		}
+		wait := retryWait(page, attempt, opts.RetryDelay)
+		currentLogger().Debug("retrying", "attempt", attempt+1, "wait", wait, "err", err)
+		if !sleep(ctx, wait) {
+			return page, err
+		}
+	}
+}
+
+// sleep pauses for d and reports whether it did so without ctx being cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isRetryable reports whether a failed fetch is worth another attempt.
+// Responses are judged by status code; without a response, only errors raised while
+// performing the request (not while building it) are considered transient.
+func isRetryable(page fetchedPage, err error) bool {
+	if page.status != 0 {
+		return retryableStatus[page.status]
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Op != "parse"
+}
+
+// retryWait computes how long to wait before the next attempt. A Retry-After header on a
+// 429 response is honored; otherwise the wait is baseDelay * 2^attempt, capped at
+// maxRetryWait, with the upper half randomized to avoid synchronized retries.
+func retryWait(page fetchedPage, attempt int, baseDelay time.Duration) time.Duration {
+	if page.status == http.StatusTooManyRequests {
+		if wait, ok := parseRetryAfter(page.header.Get("Retry-After")); ok {
+			return min(wait, maxRetryWait)
+		}
+	}
+
+	backoff := maxRetryWait
+	if attempt < 30 { // Avoid overflowing the shift for absurd retry counts
+		backoff = min(baseDelay<<attempt, maxRetryWait)
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter interprets a Retry-After header, which is either a number of seconds
+// or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,395 @@
+package parse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is the User-Agent sent with every request and used to select
+// the matching group of rules in robots.txt.
+const DefaultUserAgent = "Mozilla/5.0 (compatible; SitemapBuilder/1.0)"
+
+// ErrRobotsUnreachable is wrapped by the error NewRobotsFilter and RobotsFilter.For return
+// when a robots.txt cannot be fetched or answers with a server error. RFC 9309 then
+// requires the crawler to assume that the whole host is disallowed, which the filter
+// returned alongside the error does unless AllowUnreachable was called.
+var ErrRobotsUnreachable = errors.New("robots.txt unreachable")
+
+// RobotsFilter decides whether URLs may be crawled according to a site's robots.txt.
+// It holds the Allow and Disallow rules that apply to a single user agent on the host it
+// was created for, and fetches the robots.txt of any other host the first time a URL on
+// that host is checked, so that subdomains and additional start URLs follow their own
+// rules. A RobotsFilter is safe for concurrent use.
+type RobotsFilter struct {
+	host        string        // Host (including port) the rules apply to
+	rules       []robotsRule  // Allow/Disallow rules for the selected user agent
+	crawlDelay  time.Duration // Crawl-delay requested for the selected user agent
+	sitemaps    []string      // Sitemap URLs advertised for the whole site
+	unreachable bool          // robots.txt could not be read, so the host is disallowed
+
+	ctx              context.Context        // Aborts the fetches of the robots.txt of other hosts
+	client           *http.Client           // Fetches the robots.txt of other hosts
+	opts             CrawlOptions           // Headers, User-Agent and credentials of those fetches
+	allowUnreachable bool                   // Hosts whose robots.txt cannot be read are allowed instead
+	mu               sync.Mutex             // Guards others
+	others           map[string]*robotsHost // robots.txt of every other host checked so far
+}
+
+// robotsHost is the robots.txt of a host other than the one of a RobotsFilter, fetched once.
+type robotsHost struct {
+	once   sync.Once
+	filter *RobotsFilter // Rules of the host
+	err    error         // Why the robots.txt of the host could not be read, if it could not
+}
+
+// robotsRule is a single Allow or Disallow directive.
+type robotsRule struct {
+	allow   bool   // true for Allow, false for Disallow
+	pattern string // Path pattern, possibly containing '*' wildcards and a trailing '$'
+}
+
+// robotsGroup is a set of rules sharing one or more User-agent lines.
+type robotsGroup struct {
+	agents     []string // Lowercased user agent tokens of the group
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// NewRobotsFilter fetches /robots.txt from the root of baseURL with the crawl's headers,
+// User-Agent and credentials, and builds a filter from the rules that apply to that
+// User-Agent. Groups naming the crawler take precedence over the wildcard "*" group. As RFC 9309 prescribes, a missing robots.txt (any 4xx response)
+// allows everything, while one that cannot be fetched or answers with a server error
+// disallows everything: the filter is then returned together with an error wrapping
+// ErrRobotsUnreachable.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the fetch, and the later fetches of For
+//   - baseURL: Any URL on the site; only its scheme and host are used
+//   - client: HTTP client used to download robots.txt
+//   - opts: Request settings of the crawl, such as the User-Agent and custom headers
+//
+// Returns:
+//   - *RobotsFilter: Filter for URLs on the site's host; nil only for an invalid baseURL
+//     or request
+//   - error: Any error that occurred while fetching robots.txt, or a 5xx response
+func NewRobotsFilter(ctx context.Context, baseURL string, client *http.Client, opts CrawlOptions) (*RobotsFilter, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q for robots.txt", baseURL)
+	}
+	filter, err := fetchRobots(ctx, base, client, opts)
+	if filter == nil {
+		return nil, err
+	}
+	filter.ctx, filter.client, filter.opts = ctx, client, opts
+	filter.others = make(map[string]*robotsHost)
+	return filter, err
+}
+
+// fetchRobots downloads and parses the robots.txt of the scheme and host of base. A
+// robots.txt that cannot be read yields a filter disallowing the host along with an error
+// wrapping ErrRobotsUnreachable.
+func fetchRobots(ctx context.Context, base *url.URL, client *http.Client, opts CrawlOptions) (*RobotsFilter, error) {
+	robotsURL := base.Scheme + "://" + base.Host + "/robots.txt"
+
+	req, err := newRequest(ctx, "GET", robotsURL, client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &RobotsFilter{host: base.Host}
+	unreachable := &RobotsFilter{host: base.Host, unreachable: true}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return unreachable, fmt.Errorf("%w: fetching %s: %w", ErrRobotsUnreachable, robotsURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		groups, sitemaps, err := parseRobots(resp.Body)
+		if err != nil {
+			return unreachable, fmt.Errorf("%w: reading %s: %w", ErrRobotsUnreachable, robotsURL, err)
+		}
+		filter.rules, filter.crawlDelay = selectRobotsRules(groups, opts.userAgent())
+		filter.sitemaps = sitemaps
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt: everything is allowed
+	default:
+		return unreachable, fmt.Errorf("%w: fetching %s: received status code %d", ErrRobotsUnreachable, robotsURL, resp.StatusCode)
+	}
+
+	return filter, nil
+}
+
+// AllowUnreachable makes the filter allow every URL on a host whose robots.txt cannot
+// be read, its own host included, as if the host had no robots.txt. RFC 9309 asks
+// crawlers to assume the opposite, so only call it for sites you are responsible for, and
+// before the filter is used.
+func (f *RobotsFilter) AllowUnreachable() {
+	if f != nil {
+		f.allowUnreachable = true
+	}
+}
+
+// Allowed reports whether rawURL may be crawled. URLs on other hosts are checked against
+// the robots.txt of their own host, see For, and nothing is allowed on a host whose
+// robots.txt cannot be read. When several rules match, the most specific (longest)
+// pattern wins, and Allow wins over Disallow for patterns of equal length.
+func (f *RobotsFilter) Allowed(rawURL string) bool {
+	if f == nil {
+		return true
+	}
+	allowUnreachable := f.allowUnreachable
+	f, _ = f.For(rawURL)
+	if f.unreachable {
+		return allowUnreachable
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	// Rules are matched against the percent-encoded path plus query string
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, bestLen := true, -1
+	for _, rule := range f.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > bestLen || (len(rule.pattern) == bestLen && rule.allow) {
+			allowed, bestLen = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// For returns the filter for the host of rawURL: f itself for the host it was created
+// for, or the filter built from the robots.txt of another host, fetched with the scheme
+// of rawURL the first time that host is asked for and kept for later calls. If that
+// robots.txt cannot be fetched or answers with a server error, the returned filter
+// disallows the whole host, unless AllowUnreachable was called, and the error wraps
+// ErrRobotsUnreachable. It is fetched like the first one, with the context and request
+// settings given to NewRobotsFilter, so a host first reached after that context ended
+// counts as unreachable.
+//
+// Parameters:
+//   - rawURL: Any URL on the host
+//
+// Returns:
+//   - *RobotsFilter: The rules of the host of rawURL; nil if f is nil
+//   - error: Why the robots.txt of another host could not be read, on every call for it
+func (f *RobotsFilter) For(rawURL string) (*RobotsFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || strings.EqualFold(u.Host, f.host) || f.others == nil {
+		return f, nil
+	}
+
+	key := strings.ToLower(u.Host)
+	f.mu.Lock()
+	host, ok := f.others[key]
+	if !ok {
+		host = &robotsHost{}
+		f.others[key] = host
+	}
+	f.mu.Unlock()
+
+	// Fetch outside the lock, so checks for other hosts need not wait for this one
+	host.once.Do(func() {
+		host.filter, host.err = fetchRobots(f.ctx, u, f.client, f.opts)
+		switch {
+		case host.filter == nil:
+			currentLogger().Warn("ignoring robots.txt", "host", u.Host, "err", host.err)
+			host.filter = &RobotsFilter{host: u.Host}
+		case host.filter.unreachable && !f.allowUnreachable:
+			currentLogger().Warn("robots.txt unreachable, skipping the host", "host", u.Host, "err", host.err)
+		case host.err != nil:
+			currentLogger().Warn("robots.txt unreachable, crawling the host unrestricted", "host", u.Host, "err", host.err)
+		}
+	})
+	return host.filter, host.err
+}
+
+// CrawlDelay returns the Crawl-delay requested for the crawler's user agent,
+// or zero if robots.txt does not specify one.
+func (f *RobotsFilter) CrawlDelay() time.Duration {
+	if f == nil {
+		return 0
+	}
+	return f.crawlDelay
+}
+
+// Sitemaps returns the sitemap URLs advertised with Sitemap lines, in document order.
+func (f *RobotsFilter) Sitemaps() []string {
+	if f == nil {
+		return nil
+	}
+	return f.sitemaps
+}
+
+// DiscoverSitemap fetches /robots.txt from the root of baseURL and returns the URLs of
+// all sitemaps it advertises with Sitemap lines, which apply regardless of user agent.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the fetch
+//   - baseURL: Any URL on the site; only its scheme and host are used
+//   - client: HTTP client used to download robots.txt
+//   - opts: Request settings of the crawl, such as the User-Agent and custom headers
+//
+// Returns:
+//   - []string: The advertised sitemap URLs in document order, empty without a robots.txt
+//   - error: Any error that occurred while fetching robots.txt, or a 5xx response
+func DiscoverSitemap(ctx context.Context, baseURL string, client *http.Client, opts CrawlOptions) ([]string, error) {
+	filter, err := NewRobotsFilter(ctx, baseURL, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Sitemaps(), nil
+}
+
+// parseRobots splits a robots.txt document into user agent groups and collects the
+// sitemap URLs it advertises. Comments and unknown directives are ignored. Consecutive
+// User-agent lines share a group, and a User-agent line following any rule starts a new
+// group; Sitemap lines belong to no group and may appear anywhere.
+func parseRobots(r io.Reader) ([]robotsGroup, []string, error) {
+	var groups []robotsGroup
+	var sitemaps []string
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "sitemap" {
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+			continue
+		}
+
+		if key == "user-agent" {
+			if !inAgents {
+				groups = append(groups, robotsGroup{})
+				current = &groups[len(groups)-1]
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			continue
+		}
+
+		inAgents = false
+		if current == nil {
+			continue // Rules before any User-agent line belong to no group
+		}
+
+		switch key {
+		case "allow", "disallow":
+			// An empty Disallow means "allow everything" and adds no rule
+			if value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	return groups, sitemaps, scanner.Err()
+}
+
+// selectRobotsRules merges the groups that apply to userAgent. A group applies when one of
+// its agent tokens appears in the User-Agent string; if no group names the crawler, the
+// wildcard "*" groups are used instead.
+func selectRobotsRules(groups []robotsGroup, userAgent string) ([]robotsRule, time.Duration) {
+	ua := strings.ToLower(userAgent)
+
+	var specific, wildcard []robotsGroup
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if agent != "" && strings.Contains(ua, agent) {
+				specific = append(specific, g)
+				break
+			}
+		}
+	}
+
+	selected := specific
+	if len(selected) == 0 {
+		selected = wildcard
+	}
+
+	var rules []robotsRule
+	var delay time.Duration
+	for _, g := range selected {
+		rules = append(rules, g.rules...)
+		delay = max(delay, g.crawlDelay)
+	}
+	return rules, delay
+}
+
+// matchRobotsPattern reports whether path matches a robots.txt pattern. Patterns match
+// path prefixes, '*' matches any sequence of characters, and a trailing '$' anchors the
+// pattern to the end of the path.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part must match at the very beginning of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	// Each following part may appear anywhere after the previous one
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
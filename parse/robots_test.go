@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRobotsFilterFetchesOtherHosts(t *testing.T) {
+	home := testSite{
+		"/robots.txt": "User-agent: *\nDisallow: /admin\n",
+	}.serve(t)
+	other := testSite{
+		"/robots.txt": "User-agent: *\nDisallow: /private\nCrawl-delay: 2\n",
+	}.serve(t)
+	missing := testSite{}.serve(t) // No robots.txt at all
+
+	robots, err := NewRobotsFilter(context.Background(), home.URL, http.DefaultClient, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("NewRobotsFilter: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{home.URL + "/admin/users", false},
+		{home.URL + "/private", true},
+		{other.URL + "/private/page", false},
+		{other.URL + "/admin", true},
+		{missing.URL + "/anything", true},
+	}
+	for _, tt := range tests {
+		if got := robots.Allowed(tt.url); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	otherRobots, err := robots.For(other.URL + "/")
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if got := otherRobots.CrawlDelay(); got != 2*time.Second {
+		t.Errorf("Crawl-delay of the other host = %s, want 2s", got)
+	}
+	if again, _ := robots.For(other.URL + "/x"); again != otherRobots {
+		t.Error("For fetched the robots.txt of the other host again")
+	}
+	if self, _ := robots.For(home.URL + "/x"); self != robots {
+		t.Error("For did not return the filter itself for its own host")
+	}
+}
+
+func TestRobotsFilterUnreachable(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	missing := testSite{}.serve(t) // No robots.txt at all
+
+	for name, srv := range map[string]*httptest.Server{"5xx": failing, "network error": closed} {
+		robots, err := NewRobotsFilter(context.Background(), srv.URL, http.DefaultClient, CrawlOptions{})
+		if !errors.Is(err, ErrRobotsUnreachable) {
+			t.Errorf("%s: NewRobotsFilter error = %v, want ErrRobotsUnreachable", name, err)
+		}
+		if robots.Allowed(srv.URL + "/") {
+			t.Errorf("%s: the site is allowed although its robots.txt is unreachable", name)
+		}
+		robots.AllowUnreachable()
+		if !robots.Allowed(srv.URL + "/") {
+			t.Errorf("%s: the site is disallowed after AllowUnreachable", name)
+		}
+	}
+
+	// Other hosts whose robots.txt is unreachable are disallowed in the same way
+	for _, allow := range []bool{false, true} {
+		robots, err := NewRobotsFilter(context.Background(), missing.URL, http.DefaultClient, CrawlOptions{})
+		if err != nil {
+			t.Fatalf("NewRobotsFilter without robots.txt: %v", err)
+		}
+		if allow {
+			robots.AllowUnreachable()
+		}
+		if _, err := robots.For(failing.URL + "/"); !errors.Is(err, ErrRobotsUnreachable) {
+			t.Errorf("For error = %v, want ErrRobotsUnreachable", err)
+		}
+		if got := robots.Allowed(failing.URL + "/page"); got != allow {
+			t.Errorf("with AllowUnreachable %v, another host with an unreachable robots.txt allowed = %v", allow, got)
+		}
+		if !robots.Allowed(missing.URL + "/page") {
+			t.Error("the host without robots.txt is disallowed")
+		}
+	}
+}
+
+func TestRobotsFilterSendsCrawlRequestSettings(t *testing.T) {
+	site := testSite{"/robots.txt": "User-agent: MyBot\nDisallow: /admin\n"}
+	protected := func(w http.ResponseWriter, r *http.Request) {
+		// Like a staging site behind a login, robots.txt is only served to the crawl
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "staging" || pass != "secret" || r.Header.Get("X-Token") != "abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		site.ServeHTTP(w, r)
+	}
+	home := httptest.NewServer(http.HandlerFunc(protected))
+	defer home.Close()
+	other := httptest.NewServer(http.HandlerFunc(protected))
+	defer other.Close()
+
+	opts := CrawlOptions{
+		UserAgent: "MyBot/2.0",
+		Headers:   map[string]string{"X-Token": "abc"},
+		BasicAuth: &BasicAuthConfig{Username: "staging", Password: "secret"},
+	}
+	robots, err := NewRobotsFilter(context.Background(), home.URL, http.DefaultClient, opts)
+	if err != nil {
+		t.Fatalf("NewRobotsFilter: %v", err)
+	}
+	for _, srv := range []*httptest.Server{home, other} {
+		if robots.Allowed(srv.URL + "/admin") {
+			t.Errorf("%s/admin is allowed, want the rules read with the crawl's credentials", srv.URL)
+		}
+	}
+}
+
+func TestRobotsFilterHonorsContext(t *testing.T) {
+	srv := testSite{"/robots.txt": "User-agent: *\nDisallow: /admin\n"}.serve(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewRobotsFilter(ctx, srv.URL, http.DefaultClient, CrawlOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewRobotsFilter error = %v, want the cancellation", err)
+	}
+}
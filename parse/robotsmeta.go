@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ParseRobotsMetaTag scans the document's <head> for <meta name="robots"> and
+// <meta name="googlebot"> tags and reports whether they carry the noindex and nofollow
+// directives. Names and directives are matched case-insensitively, and the "none"
+// directive implies both. Several robots meta tags are combined.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//
+// Returns:
+//   - noindex: true if the page asks not to be indexed
+//   - nofollow: true if the page asks that its links not be followed
+func ParseRobotsMetaTag(n *html.Node) (noindex, nofollow bool) {
+	head := findElement(n, atom.Head)
+	if head == nil {
+		return false, false
+	}
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Meta && isRobotsMetaName(attrValue(node, "name")) {
+			ni, nf := parseRobotsDirectives(attrValue(node, "content"))
+			noindex = noindex || ni
+			nofollow = nofollow || nf
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(head)
+	return noindex, nofollow
+}
+
+// isRobotsMetaName reports whether a <meta> name addresses crawlers, either all of them
+// or Google's in particular.
+func isRobotsMetaName(name string) bool {
+	name = strings.TrimSpace(name)
+	return strings.EqualFold(name, "robots") || strings.EqualFold(name, "googlebot")
+}
+
+// ParseXRobotsHeader interprets the value of an X-Robots-Tag response header, which uses
+// the same directives as the robots meta tag. Directives may be prefixed with a user agent
+// ("googlebot: noindex"); such prefixes are ignored and the directive is applied as is.
+//
+// Parameters:
+//   - value: Header value; multiple headers can be joined with commas
+//
+// Returns:
+//   - noindex: true if the response asks not to be indexed
+//   - nofollow: true if the response asks that its links not be followed
+func ParseXRobotsHeader(value string) (noindex, nofollow bool) {
+	return parseRobotsDirectives(value)
+}
+
+// parseRobotsDirectives evaluates a comma-separated list of robots directives,
+// case-insensitively.
+func parseRobotsDirectives(content string) (noindex, nofollow bool) {
+	for _, directive := range strings.Split(content, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		// Strip an optional "useragent:" prefix
+		if _, rest, ok := strings.Cut(directive, ":"); ok {
+			directive = strings.TrimSpace(rest)
+		}
+
+		switch directive {
+		case "noindex":
+			noindex = true
+		case "nofollow":
+			nofollow = true
+		case "none":
+			noindex, nofollow = true, true
+		}
+	}
+	return noindex, nofollow
+}
+
+// findElement returns the first element of the given type in document order, or nil.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
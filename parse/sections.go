@@ -0,0 +1,78 @@
+package parse
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RootSection is the name of the section holding URLs without a path segment, such as
+// the home page.
+const RootSection = "root"
+
+// Section is a group of links sharing the same first path segment.
+type Section struct {
+	Name  string // Sanitized first path segment, safe to use in a file name
+	Links []Link // Links of the section, in their original order
+}
+
+// GroupBySection partitions links by the first segment of their path, so that
+// https://example.com/blog/post and https://example.com/blog both belong to section
+// "blog". URLs at the root of the site belong to RootSection.
+//
+// Parameters:
+//   - links: The links to group
+//
+// Returns:
+//   - []Section: The non-empty sections, sorted by name
+func GroupBySection(links []Link) []Section {
+	var sections []Section
+	for _, link := range links {
+		name := SectionName(link.Href)
+		i, found := slices.BinarySearchFunc(sections, name, func(s Section, name string) int {
+			return cmp.Compare(s.Name, name)
+		})
+		if !found {
+			sections = slices.Insert(sections, i, Section{Name: name})
+		}
+		sections[i].Links = append(sections[i].Links, link)
+	}
+	return sections
+}
+
+// SectionName returns the sanitized first path segment of rawURL. The segment is
+// unescaped and lowercased, every run of characters other than ASCII letters, digits,
+// '-' and '_' becomes a single '-', and leading or trailing dashes are removed.
+// RootSection is returned when nothing usable is left.
+//
+// Parameters:
+//   - rawURL: Absolute URL of a page
+//
+// Returns:
+//   - string: A name safe to embed in a file name
+func SectionName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RootSection
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(segment) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	name := strings.Trim(b.String(), "-")
+	if name == "" {
+		return RootSection
+	}
+	return name
+}
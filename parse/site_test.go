@@ -0,0 +1,70 @@
+package parse
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testSite is a fixture website served by httptest, mapping paths to page bodies. Paths
+// ending in .pdf are served as application/pdf, every other path as text/html; paths
+// missing from the map answer 404.
+type testSite map[string]string
+
+// serve starts the site and stops it when the test ends.
+func (site testSite) serve(t testing.TB) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(site)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// ServeHTTP answers with the page stored for the path of r.
+func (site testSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := site[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if path.Ext(r.URL.Path) == ".pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.Write([]byte(body))
+}
+
+// crawlHrefs returns the URLs listed by a crawl, in listing order.
+func crawlHrefs(result *CrawlResult) []string {
+	hrefs := make([]string, len(result.Links))
+	for i, link := range result.Links {
+		hrefs[i] = link.Href
+	}
+	return hrefs
+}
+
+// treeOfPages returns a site of n pages forming a binary tree below "/", every page also
+// linking back to the start page.
+func treeOfPages(n int) testSite {
+	site := make(testSite, n)
+	page := func(i int) string {
+		if i == 0 {
+			return "/"
+		}
+		return fmt.Sprintf("/p/%d", i)
+	}
+	for i := range n {
+		var body strings.Builder
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < n {
+				fmt.Fprintf(&body, `<a href="%s">Page %d</a> `, page(child), child)
+			}
+		}
+		body.WriteString(`<a href="/">Home</a>`)
+		site[page(i)] = body.String()
+	}
+	return site
+}
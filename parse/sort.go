@@ -0,0 +1,32 @@
+package parse
+
+import (
+	"slices"
+	"strings"
+)
+
+// SortLinks returns the links ordered lexicographically by URL, with ties broken by
+// crawl depth, and with duplicate URLs collapsed into their shallowest occurrence.
+// Sorted output does not depend on the order in which pages happened to be discovered,
+// so repeated crawls of an unchanged site produce identical files.
+//
+// Parameters:
+//   - links: The links to sort; the slice itself is left unchanged
+//
+// Returns:
+//   - []Link: A sorted copy of links without duplicate URLs
+func SortLinks(links []Link) []Link {
+	sorted := slices.Clone(links)
+	slices.SortStableFunc(sorted, compareLinks)
+	return slices.CompactFunc(sorted, func(a, b Link) bool {
+		return a.Href == b.Href
+	})
+}
+
+// compareLinks orders links by URL and then by depth.
+func compareLinks(a, b Link) int {
+	if c := strings.Compare(a.Href, b.Href); c != 0 {
+		return c
+	}
+	return a.Depth - b.Depth
+}
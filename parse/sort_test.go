@@ -0,0 +1,57 @@
+package parse
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSortLinks(t *testing.T) {
+	links := []Link{
+		{Href: "https://example.com/b", Depth: 2},
+		{Href: "https://example.com/a", Depth: 1},
+		{Href: "https://example.com/b", Depth: 1, Text: "shallow"},
+		{Href: "https://example.com/", Depth: 0},
+	}
+	got := SortLinks(links)
+
+	want := []string{"https://example.com/", "https://example.com/a", "https://example.com/b"}
+	if hrefs := linkHrefs(got); !slices.Equal(hrefs, want) {
+		t.Fatalf("SortLinks = %v, want %v", hrefs, want)
+	}
+	if got[2].Depth != 1 || got[2].Text != "shallow" {
+		t.Errorf("kept %+v for the duplicate, want its shallowest occurrence", got[2])
+	}
+	if links[0].Href != "https://example.com/b" {
+		t.Error("SortLinks reordered its argument")
+	}
+}
+
+func TestSortedOutputIsReproducible(t *testing.T) {
+	srv := testSite{
+		"/":        `<a href="/zebra">Zebra</a> <a href="/apple">Apple</a> <a href="/mango">Mango</a>`,
+		"/zebra":   `<a href="/zebra/1">1</a> <a href="/zebra/2">2</a> <a href="/apple">Apple</a>`,
+		"/apple":   `<a href="/apple/1">1</a> <a href="/mango">Mango</a>`,
+		"/mango":   `<a href="/zebra/2">2</a> <a href="/apple/1">1</a>`,
+		"/zebra/1": `<p>1</p>`,
+		"/zebra/2": `<p>2</p>`,
+		"/apple/1": `<p>1</p>`,
+	}.serve(t)
+
+	var first string
+	for run := range 5 {
+		result, err := CrawlBFS(context.Background(), []Link{{Href: srv.URL + "/"}}, WithConcurrency(8))
+		if err != nil {
+			t.Fatalf("CrawlBFS: %v", err)
+		}
+		out, err := EncodeXML(SortLinks(result.Links))
+		if err != nil {
+			t.Fatalf("EncodeXML: %v", err)
+		}
+		if run == 0 {
+			first = out
+		} else if out != first {
+			t.Fatalf("run %d wrote:\n%s\nrun 0 wrote:\n%s", run, out, first)
+		}
+	}
+}
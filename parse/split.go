@@ -0,0 +1,119 @@
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// sitemapSizeMargin is the number of bytes kept free in every sitemap file below
+// MaxSitemapBytes, so small differences in the document envelope can never push a
+// file over the limit.
+const sitemapSizeMargin = 4096
+
+// ChunkBySize partitions links into consecutive groups that each encode to a valid
+// sitemap file: at most maxURLs entries and at most maxBytes bytes of uncompressed XML,
+// including the XML declaration, the <urlset> envelope and a safety margin. The byte
+// cost of every <url> entry is measured before it is committed to a chunk, so a new
+// chunk is started as soon as the next entry would not fit.
+//
+// Parameters:
+//   - links: The links to partition
+//   - maxURLs: Maximum number of entries per chunk (must be positive)
+//   - maxBytes: Maximum encoded size of each chunk's sitemap document
+//
+// Returns:
+//   - [][]Link: The chunks in their original order, sharing the backing array of links
+//   - error: A single entry that cannot fit into any file, or an XML encoding error
+func ChunkBySize(links []Link, maxURLs, maxBytes int) ([][]Link, error) {
+	if maxURLs <= 0 {
+		return nil, fmt.Errorf("max URLs per file must be positive, got %d", maxURLs)
+	}
+
+	// Every document pays for the declaration, the envelope and the margin
+	overhead, err := urlsetOverhead()
+	if err != nil {
+		return nil, err
+	}
+	budget := maxBytes - overhead - sitemapSizeMargin
+
+	var chunks [][]Link
+	start, size := 0, 0
+	for i, link := range links {
+		// Entries WriteXML will skip as malformed take up no space
+		u, err := urlFromLink(link)
+		if err != nil {
+			continue
+		}
+		u.Comment = titleComment(link.Title)
+		cost, err := urlEntrySize(u)
+		if err != nil {
+			return nil, err
+		}
+		if cost > budget {
+			return nil, fmt.Errorf("sitemap entry for %s is %d bytes, too large for a %d byte sitemap", link.Href, cost, maxBytes)
+		}
+
+		// Roll over to a new chunk when either limit would be exceeded
+		if i > start && (i-start == maxURLs || size+cost > budget) {
+			chunks = append(chunks, links[start:i])
+			start, size = i, 0
+		}
+		size += cost
+	}
+	if start < len(links) {
+		chunks = append(chunks, links[start:])
+	}
+
+	return chunks, nil
+}
+
+// urlEntrySize returns the exact number of bytes u occupies inside a <urlset> written
+// by WriteXMLExtended: a newline, then the entry indented one level with its title comment.
+// Compact output, or output without titles, is never larger, so the measurement bounds
+// every XML writer of this package.
+func urlEntrySize(u Url) (int, error) {
+	var cw countingWriter
+	enc := xml.NewEncoder(&cw)
+	enc.Indent("  ", "  ")
+	if err := enc.Encode(u); err != nil {
+		return 0, fmt.Errorf("measuring sitemap entry %s: %w", u.Loc, err)
+	}
+	if err := enc.Close(); err != nil {
+		return 0, err
+	}
+	return cw.n + 1, nil
+}
+
+// urlsetOverhead returns the size of a sitemap document without any entries: the XML
+// declaration, the <urlset> tags with every extension namespace declared, the newline
+// before the closing tag and the trailing newline of a written file. Declaring all
+// namespaces gives an upper bound regardless of which extensions a chunk uses.
+func urlsetOverhead() (int, error) {
+	urlset := Urlset{
+		Xmlns:      sitemapNamespace,
+		XmlnsImage: imageNamespace,
+		XmlnsVideo: videoNamespace,
+		XmlnsXhtml: xhtmlNamespace,
+	}
+
+	var cw countingWriter
+	enc := xml.NewEncoder(&cw)
+	if err := enc.Encode(urlset); err != nil {
+		return 0, fmt.Errorf("measuring sitemap envelope: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return 0, err
+	}
+	return len(xml.Header) + cw.n + 2, nil
+}
+
+// countingWriter discards everything written to it while counting the bytes.
+type countingWriter struct {
+	n int
+}
+
+// Write implements io.Writer.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
@@ -0,0 +1,60 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// MaxSitemapBytes is the maximum uncompressed size of a single sitemap file
+// according to the sitemap protocol (50MB).
+const MaxSitemapBytes = 50 * 1024 * 1024
+
+// EncodeText writes links as a plain-text sitemap: one UTF-8 URL per line, each
+// terminated by a single LF, with no header or namespace of any kind. This is the
+// simplest format accepted by the sitemap protocol and is convenient for feeding
+// URL lists to other tools.
+//
+// Duplicate URLs are dropped, keeping the first occurrence. The protocol limits of
+// MaxURLsPerSitemap URLs and MaxSitemapBytes bytes are enforced up front, so nothing
+// is written when the links would produce an invalid file.
+//
+// Parameters:
+//   - links: Slice of Link structs containing the URLs to include in the sitemap
+//   - w: Destination for the text sitemap
+//
+// Returns:
+//   - error: A limit violation or any error that occurred while writing
+func EncodeText(links []Link, w io.Writer) error {
+	// Collect unique URLs and measure the resulting file before writing anything
+	seen := make(map[string]struct{}, len(links))
+	var urls []string
+	size := 0
+	for _, link := range links {
+		if _, exists := seen[link.Href]; exists {
+			continue
+		}
+		seen[link.Href] = struct{}{}
+		urls = append(urls, link.Href)
+		size += len(link.Href) + 1 // URL plus its LF terminator
+	}
+
+	if len(urls) > MaxURLsPerSitemap {
+		return fmt.Errorf("text sitemap has %d URLs, exceeding the limit of %d", len(urls), MaxURLsPerSitemap)
+	}
+	if size > MaxSitemapBytes {
+		return fmt.Errorf("text sitemap is %d bytes, exceeding the limit of %d", size, MaxSitemapBytes)
+	}
+
+	// Buffer the many small line writes
+	bw := bufio.NewWriter(w)
+	for _, u := range urls {
+		if _, err := bw.WriteString(u + "\n"); err != nil {
+			return fmt.Errorf("writing text sitemap: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing text sitemap: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// LinkStatus is the outcome of checking a single URL with ValidateLinks.
+type LinkStatus struct {
+	Href       string // The checked URL
+	StatusCode int    // HTTP status code of the final response, 0 if none was received
+	Error      string // Why the URL is not available, empty if it returned 200
+}
+
+// Broken reports whether the URL answered with a client or server error (4xx or 5xx),
+// or could not be reached at all.
+func (s LinkStatus) Broken() bool {
+	return s.StatusCode >= http.StatusBadRequest || (s.StatusCode == 0 && s.Error != "")
+}
+
+// ValidateLinks checks the availability of every link without crawling it: a HEAD request
+// is sent, falling back to GET for servers that do not support HEAD. It is meant to be run
+// against a finished crawl or an existing sitemap to find dead links.
+//
+// The links are checked by a pool of opts.Concurrency workers, each honoring opts.Delay,
+// and opts.Limiter and opts.MaxRetries apply as during a crawl. Cancelling ctx stops the
+// remaining checks, which are reported with the context error.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the checks
+//   - links: The links to check
+//   - client: HTTP client for making requests; nil builds one with NewHTTPClient(opts)
+//   - opts: Request settings such as the User-Agent, and the worker pool size
+//
+// Returns:
+//   - []LinkStatus: One status per link, in the same order as links
+//   - error: ctx.Err() if the checks were cancelled, nil otherwise
+func ValidateLinks(ctx context.Context, links []Link, client *http.Client, opts CrawlOptions) ([]LinkStatus, error) {
+	if client == nil {
+		client = NewHTTPClient(opts)
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+
+	statuses := make([]LinkStatus, len(links))
+	jobs := make(chan int)
+
+	// Start the worker pool; each worker owns its politeness delay as during a crawl
+	var wg sync.WaitGroup
+	for range min(max(opts.Concurrency, 1), len(links)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := pacer{delay: opts.Delay}
+			for i := range jobs {
+				statuses[i] = validateLink(ctx, links[i].Href, client, opts, &p)
+			}
+		}()
+	}
+
+	for i := range links {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return statuses, ctx.Err()
+}
+
+// validateLink checks a single URL for ValidateLinks.
+func validateLink(ctx context.Context, href string, client *http.Client, opts CrawlOptions, p *pacer) LinkStatus {
+	status := LinkStatus{Href: href}
+	page, err := withRetry(ctx, opts, func() (fetchedPage, error) {
+		p.wait(ctx)
+		if opts.Limiter != nil {
+			if err := opts.Limiter.Wait(ctx, hostOf(href)); err != nil {
+				return fetchedPage{}, err
+			}
+		}
+		return verifyPage(ctx, href, client, opts)
+	})
+	status.StatusCode = page.status
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
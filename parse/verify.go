@@ -0,0 +1,76 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// verifyPage checks that url can be served without fetching its body: a HEAD request is
+// sent first, and servers that do not support HEAD are asked again with a full GET.
+// The returned page carries the status, headers and final location but no document.
+//
+// Parameters:
+//   - ctx: Context whose cancellation aborts the request
+//   - url: The URL to verify
+//   - client: HTTP client with configured timeout and other settings
+//   - opts: Request settings such as the User-Agent
+//
+// Returns:
+//   - fetchedPage: Response metadata of the final request
+//   - error: Any error that occurred, including a non-200 final status
+func verifyPage(ctx context.Context, url string, client *http.Client, opts CrawlOptions) (fetchedPage, error) {
+	var page fetchedPage
+
+	req, err := newRequest(ctx, "HEAD", url, client, opts)
+	if err != nil {
+		return page, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return page, fmt.Errorf("verifying URL %s: %w", url, err)
+	}
+	resp.Body.Close()
+
+	// Fall back to GET for servers that reject or do not implement HEAD
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		page, err = fetchPage(ctx, url, client, opts)
+		page.doc = nil
+		if errors.Is(err, ErrNotHTML) {
+			err = nil // Served all the same
+		}
+		return page, err
+	}
+
+	page.status = resp.StatusCode
+	page.header = resp.Header
+	page.location = resp.Request.URL.String()
+	page.contentType = resp.Header.Get("Content-Type")
+	page.size = resp.ContentLength
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("verifying URL %s: received status code %d", url, resp.StatusCode)
+	}
+	return page, nil
+}
+
+// checkRedirect returns an error if the final response for rawURL came from a different
+// URL, meaning rawURL itself redirects. Fragments are ignored since they are never sent.
+func checkRedirect(page fetchedPage, rawURL string) error {
+	if page.location == "" || withoutFragment(page.location) == withoutFragment(rawURL) {
+		return nil
+	}
+	return fmt.Errorf("%s redirects to %s", rawURL, page.location)
+}
+
+// withoutFragment returns rawURL with any #fragment removed.
+func withoutFragment(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
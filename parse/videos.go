@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"net/url"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// videoNamespace is the XML namespace of Google's video sitemap extension.
+const videoNamespace = "http://www.google.com/schemas/sitemap-video/1.1"
+
+// Video represents an HTML5 video embedded in a page, serialized as a <video:video>
+// child of the page's <url> entry in the video sitemap extension. The field order
+// follows the element order of the extension's schema.
+type Video struct {
+	ThumbnailLoc string `xml:"video:thumbnail_loc,omitempty" json:"thumbnail_loc,omitempty"` // Poster image of the video
+	Title        string `xml:"video:title" json:"title"`                                     // Title of the video
+	Description  string `xml:"video:description" json:"description"`                         // Description of the video
+	ContentLoc   string `xml:"video:content_loc" json:"content_loc"`                         // Absolute URL of the video file
+}
+
+// ExtractVideos collects the <video> elements of a page. The video file is taken from the
+// element's src attribute or, failing that, from its first usable <source src> child, and
+// the poster attribute becomes the thumbnail. Videos without a title attribute fall back
+// to the page's <title>, which is also used as the description since the extension
+// requires one. Videos without any usable source are skipped.
+//
+// Parameters:
+//   - n: Root HTML node of the page
+//   - pageURL: Absolute URL of the page, used to resolve relative sources
+//
+// Returns:
+//   - []Video: Videos in document order, with duplicate sources reported once
+func ExtractVideos(n *html.Node, pageURL string) []Video {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	pageTitle := ExtractTitle(n)
+	var videos []Video
+	seen := make(map[string]struct{})
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Video {
+			video, ok := videoFromElement(node, page, pageTitle)
+			if _, exists := seen[video.ContentLoc]; ok && !exists {
+				seen[video.ContentLoc] = struct{}{}
+				videos = append(videos, video)
+			}
+			return // Nested content of <video> is fallback markup, not more videos
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	walk(n)
+	return videos
+}
+
+// videoFromElement builds a Video from a <video> element and reports whether
+// a usable content location was found.
+func videoFromElement(node *html.Node, page *url.URL, pageTitle string) (Video, bool) {
+	var video Video
+
+	if loc, ok := resolveResource(page, attrValue(node, "src")); ok {
+		video.ContentLoc = loc
+	}
+	for child := node.FirstChild; child != nil && video.ContentLoc == ""; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Source {
+			if loc, ok := resolveResource(page, attrValue(child, "src")); ok {
+				video.ContentLoc = loc
+			}
+		}
+	}
+	if video.ContentLoc == "" {
+		return video, false
+	}
+
+	if thumb, ok := resolveResource(page, attrValue(node, "poster")); ok {
+		video.ThumbnailLoc = thumb
+	}
+
+	video.Title = attrValue(node, "title")
+	if video.Title == "" {
+		video.Title = pageTitle
+	}
+	video.Description = video.Title
+
+	return video, true
+}